@@ -0,0 +1,181 @@
+package counter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/counter"
+)
+
+// store is the common contract of the in-memory and redis counters.
+type store interface {
+	Increment(key string) error
+	Allow(key string) (bool, error)
+	Reset(key string) error
+	Status(key string) (counter.Status, error)
+}
+
+type memoryStore struct {
+	c *counter.Counter
+}
+
+func (s memoryStore) Increment(key string) error {
+	return s.c.Increment(key)
+}
+
+func (s memoryStore) Allow(key string) (bool, error) {
+	return s.c.Allow(key), nil
+}
+
+func (s memoryStore) Reset(key string) error {
+	s.c.Reset(key)
+	return nil
+}
+
+func (s memoryStore) Status(key string) (counter.Status, error) {
+	return s.c.Status(key), nil
+}
+
+type redisStore struct {
+	r *counter.Redis
+}
+
+func (s redisStore) Increment(key string) error {
+	return s.r.Increment(context.Background(), key)
+}
+
+func (s redisStore) Allow(key string) (bool, error) {
+	return s.r.Allow(context.Background(), key)
+}
+
+func (s redisStore) Reset(key string) error {
+	return s.r.Reset(context.Background(), key)
+}
+
+func (s redisStore) Status(key string) (counter.Status, error) {
+	return s.r.Status(context.Background(), key)
+}
+
+func TestMemoryConformance(t *testing.T) {
+	testConformance(t, func(t *testing.T, policy counter.Policy) store {
+		c, cancel := counter.NewWithPolicy(policy)
+		t.Cleanup(cancel)
+		return memoryStore{c}
+	})
+}
+
+// testConformance checks that the counter implementations share the same
+// semantics.
+func testConformance(t *testing.T, newStore func(*testing.T, counter.Policy) store) {
+	t.Run("lock after max", func(t *testing.T) {
+		s := newStore(t, counter.Policy{Max: 3, TTL: time.Minute, Cooldown: 50 * time.Millisecond})
+
+		for i := 0; i < 2; i++ {
+			mustIncrement(t, s, "john")
+		}
+		assertAllow(t, s, "john", true)
+		assertStatus(t, s, "john", 2, 0, false)
+
+		mustIncrement(t, s, "john")
+		assertAllow(t, s, "john", false)
+		assertStatus(t, s, "john", 0, 1, true)
+
+		// Other keys are not affected.
+		assertAllow(t, s, "jane", true)
+
+		time.Sleep(100 * time.Millisecond)
+		assertAllow(t, s, "john", true)
+	})
+
+	t.Run("ignore events while locked", func(t *testing.T) {
+		s := newStore(t, counter.Policy{Max: 1, TTL: time.Minute, Cooldown: time.Minute})
+
+		for i := 0; i < 3; i++ {
+			mustIncrement(t, s, "john")
+		}
+		assertStatus(t, s, "john", 0, 1, true)
+	})
+
+	t.Run("escalation", func(t *testing.T) {
+		s := newStore(t, counter.Policy{
+			Max:        1,
+			TTL:        time.Minute,
+			Escalation: []time.Duration{50 * time.Millisecond, time.Minute},
+		})
+
+		mustIncrement(t, s, "john")
+		status := assertStatus(t, s, "john", 0, 1, true)
+		if d := time.Until(status.UnlockAt); d > 50*time.Millisecond {
+			t.Fatalf("expected first lock of 50ms, got %v", d)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		assertAllow(t, s, "john", true)
+
+		mustIncrement(t, s, "john")
+		status = assertStatus(t, s, "john", 0, 2, true)
+		if d := time.Until(status.UnlockAt); d < 50*time.Second {
+			t.Fatalf("expected second lock of 1m, got %v", d)
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		s := newStore(t, counter.Policy{Max: 1, TTL: time.Minute})
+
+		mustIncrement(t, s, "john")
+		assertAllow(t, s, "john", false)
+
+		if err := s.Reset("john"); err != nil {
+			t.Fatal(err)
+		}
+		assertAllow(t, s, "john", true)
+		assertStatus(t, s, "john", 0, 0, false)
+	})
+
+	t.Run("reset after ttl", func(t *testing.T) {
+		s := newStore(t, counter.Policy{Max: 2, TTL: 50 * time.Millisecond})
+
+		mustIncrement(t, s, "john")
+		assertStatus(t, s, "john", 1, 0, false)
+
+		time.Sleep(100 * time.Millisecond)
+		assertStatus(t, s, "john", 0, 0, false)
+
+		mustIncrement(t, s, "john")
+		assertAllow(t, s, "john", true)
+	})
+}
+
+func mustIncrement(t *testing.T, s store, key string) {
+	t.Helper()
+
+	if err := s.Increment(key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertAllow(t *testing.T, s store, key string, want bool) {
+	t.Helper()
+
+	got, err := s.Allow(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("expected allow %t, got %t", want, got)
+	}
+}
+
+func assertStatus(t *testing.T, s store, key string, count, level int, locked bool) counter.Status {
+	t.Helper()
+
+	got, err := s.Status(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Count != count || got.Level != level || got.Locked != locked {
+		t.Fatalf("expected count %d, level %d and locked %t, got %+v", count, level, locked, got)
+	}
+	return got
+}
@@ -5,98 +5,353 @@
 package counter
 
 import (
+	"container/list"
+	"errors"
 	"sync"
 	"time"
 )
 
+// ErrMaxKeys is returned when the key cannot be tracked, because the max keys
+// are reached and all tracked keys are locked.
+var ErrMaxKeys = errors.New("counter: max keys reached")
+
+// Policy limits the number of events for a key.
+type Policy struct {
+	// Max is the number of events allowed before the key is locked.
+	Max int
+
+	// TTL is the duration of inactivity after which the count and the lock
+	// level are reset. The inactivity starts from the last event, or the
+	// end of the lock, whichever is later.
+	TTL time.Duration
+
+	// Cooldown is the duration the key is locked after reaching Max.
+	// Defaults to TTL.
+	Cooldown time.Duration
+
+	// Escalation is the lock duration for each subsequent lock, e.g. 1
+	// minute for the first 5 failures, 15 minutes for the next 5, and 24
+	// hours after that. The last duration is repeated once exhausted.
+	// Overrides Cooldown when set.
+	Escalation []time.Duration
+}
+
+func (p Policy) lockDuration(level int) time.Duration {
+	if n := len(p.Escalation); n > 0 {
+		if level >= n {
+			level = n - 1
+		}
+		return p.Escalation[level]
+	}
+	if p.Cooldown > 0 {
+		return p.Cooldown
+	}
+	return p.TTL
+}
+
+// Status is the state of a key.
+type Status struct {
+	// Count is the number of events since the last lock.
+	Count int
+
+	// Level is the number of times the key has been locked.
+	Level int
+
+	// Locked is true if the key is currently locked.
+	Locked bool
+
+	// UnlockAt is the time the current lock ends. Zero if the key has never
+	// been locked.
+	UnlockAt time.Time
+}
+
 type Item struct {
-	// The identifier of the policy.
-	// id string
-	value      int
-	lastAccess time.Time
+	value       int
+	level       int
+	lastAccess  time.Time
+	lockedUntil time.Time
+
+	key   interface{}
+	elem  *list.Element
+	index int
+}
+
+func (it *Item) expired(now time.Time, ttl time.Duration) bool {
+	last := it.lastAccess
+	if it.lockedUntil.After(last) {
+		last = it.lockedUntil
+	}
+	return now.Sub(last) > ttl
+}
+
+// Stats is the summary of the counter.
+type Stats struct {
+	// Keys is the number of tracked keys.
+	Keys int
+
+	// Locked is the number of currently locked keys.
+	Locked int
+
+	// Expired is the total number of keys deleted after their ttl.
+	Expired uint64
+
+	// Evicted is the total number of keys deleted to stay within MaxKeys.
+	Evicted uint64
+
+	// Dropped is the total number of new keys not tracked because all
+	// tracked keys are locked, see ErrMaxKeys.
+	Dropped uint64
+}
+
+func (it *Item) status(now time.Time) Status {
+	return Status{
+		Count:    it.value,
+		Level:    it.level,
+		Locked:   now.Before(it.lockedUntil),
+		UnlockAt: it.lockedUntil,
+	}
 }
 
 type Counter struct {
 	sync.RWMutex
-	data map[interface{}]*Item
-	max  int
-	// TODO: Now all the items will share the same ttl and max count. It is
-	// preferable to create a separate policy for different events.
-	ttl time.Duration
+	data   map[interface{}]*Item
+	lru    *list.List
+	locks  lockHeap
+	policy Policy
+
+	maxKeys         int
+	cleanupInterval time.Duration
+	expired         uint64
+	evicted         uint64
+	dropped         uint64
+
+	sync.Once
+	quit chan interface{}
+	wg   sync.WaitGroup
 }
 
-func New(max int, ttl time.Duration) *Counter {
-	return &Counter{
-		data: make(map[interface{}]*Item),
-		max:  max,
-		ttl:  ttl,
+// Option configures the Counter.
+type Option func(*Counter)
+
+// WithMaxKeys limits the number of tracked keys. When the limit is reached,
+// the least recently used unlocked key is evicted, without scanning the keys.
+// If all keys are locked, the new key is not tracked, and is not allowed
+// until there is room again. This fails closed, so that the attempts cannot
+// go uncounted by locking enough keys, at the cost of denying new keys. Size
+// the limit above the number of keys expected to be locked at once. Defaults
+// to no limit.
+func WithMaxKeys(n int) Option {
+	return func(c *Counter) {
+		c.maxKeys = n
 	}
 }
 
-// Put(Policy{min, max, path, identifier: clientIP})
-// Should change this to put with key and value.
-func (c *Counter) Increment(key interface{}) {
+// WithCleanupInterval sets how often expired keys are deleted. Defaults to 5
+// seconds.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(c *Counter) {
+		c.cleanupInterval = interval
+	}
+}
+
+// New returns a new counter, and a function to stop the cleanup of expired
+// keys.
+func New(max int, ttl time.Duration, opts ...Option) (*Counter, func()) {
+	return NewWithPolicy(Policy{Max: max, TTL: ttl}, opts...)
+}
+
+// NewWithPolicy returns a new counter for the given policy, and a function to
+// stop the cleanup of expired keys.
+func NewWithPolicy(policy Policy, opts ...Option) (*Counter, func()) {
+	c := &Counter{
+		data:            make(map[interface{}]*Item),
+		lru:             list.New(),
+		policy:          policy,
+		cleanupInterval: 5 * time.Second,
+		quit:            make(chan interface{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.wg.Add(1)
+	go c.clean(c.cleanupInterval)
+	return c, c.cancel
+}
+
+// Increment counts an event for the key, and locks the key once the count
+// reaches the policy's Max. Events are not counted while the key is locked.
+// It returns ErrMaxKeys if the key cannot be tracked.
+func (c *Counter) Increment(key interface{}) error {
 	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
 	it, ok := c.data[key]
-	if !ok {
-		it = &Item{value: 0}
-		c.data[key] = it
+	switch {
+	case ok && it.expired(now, c.policy.TTL):
+		c.expired++
+		c.remove(it)
+		it = c.insert(key)
+	case !ok:
+		if !c.reserve(now) {
+			c.dropped++
+			return ErrMaxKeys
+		}
+		it = c.insert(key)
+	}
+	if now.Before(it.lockedUntil) {
+		return nil
 	}
 	it.value++
-	it.lastAccess = time.Now()
-	c.Unlock()
+	it.lastAccess = now
+	if it.value >= c.policy.Max {
+		it.lockedUntil = now.Add(c.policy.lockDuration(it.level))
+		it.level++
+		it.value = 0
+	}
+	c.touch(it, now)
+	return nil
 }
 
-// type Policy interface {Allow(*Item)}
-// type Policy struct {
-//         ttl time.Duration
-//         max int
-// }
-//
-// func (p *Policy) Allow(value int, lastAccess time.Time) bool {
-//         if value < p.max {
-//                 return true
-//         }
-//         if time.Since(lastAccess) > p.ttl {
-//                 return true
-//         }
-//         return false
-// }
-
+// Allow returns false while the key is locked, or while the key cannot be
+// tracked, see WithMaxKeys.
 func (c *Counter) Allow(key interface{}) bool {
 	c.Lock()
 	defer c.Unlock()
+
+	now := time.Now()
 	it, ok := c.data[key]
 	if !ok {
+		return c.hasRoom(now)
+	}
+	if it.expired(now, c.policy.TTL) {
+		c.expired++
+		c.remove(it)
 		return true
 	}
-	if it.value < c.max {
+	return !now.Before(it.lockedUntil)
+}
+
+// Reset clears the count and the lock level of the key, e.g. after a
+// successful login.
+func (c *Counter) Reset(key interface{}) {
+	c.Lock()
+	if it, ok := c.data[key]; ok {
+		c.remove(it)
+	}
+	c.Unlock()
+}
+
+// Status returns the current state of the key.
+func (c *Counter) Status(key interface{}) Status {
+	c.RLock()
+	defer c.RUnlock()
+
+	now := time.Now()
+	it, ok := c.data[key]
+	if !ok || it.expired(now, c.policy.TTL) {
+		return Status{}
+	}
+	return it.status(now)
+}
+
+// Len returns the number of tracked keys.
+func (c *Counter) Len() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.data)
+}
+
+// Stats returns the summary of the counter.
+func (c *Counter) Stats() Stats {
+	c.RLock()
+	defer c.RUnlock()
+
+	now := time.Now()
+	var locked int
+	for _, it := range c.data {
+		if now.Before(it.lockedUntil) {
+			locked++
+		}
+	}
+	return Stats{
+		Keys:    len(c.data),
+		Locked:  locked,
+		Expired: c.expired,
+		Evicted: c.evicted,
+		Dropped: c.dropped,
+	}
+}
+
+// reserve makes room for a new key by evicting the least recently used
+// unlocked key. Locked keys are never evicted, otherwise an attacker could
+// unlock themselves by flooding new keys. It returns false if there is no
+// room. Expired keys are deleted by the cleanup, or evicted first since they
+// are the least recently used.
+func (c *Counter) reserve(now time.Time) bool {
+	if c.maxKeys <= 0 || len(c.data) < c.maxKeys {
 		return true
 	}
-	if time.Since(it.lastAccess) > c.ttl {
-		delete(c.data, key)
+	it, ok := c.evictable(now)
+	if !ok {
+		return false
+	}
+	if it.expired(now, c.policy.TTL) {
+		c.expired++
+	} else {
+		c.evicted++
+	}
+	c.remove(it)
+	return true
+}
+
+// hasRoom returns true if a new key can be tracked, without making room.
+func (c *Counter) hasRoom(now time.Time) bool {
+	if c.maxKeys <= 0 || len(c.data) < c.maxKeys {
 		return true
 	}
-	return false
-}
-
-// TODO: Add a cleanup method to ensure the expired keys are deleted.
-
-// type Event struct {
-//         Name     string
-//         ClientIP string
-// }
-
-// func main() {
-//         counter := New(3, 1*time.Second)
-//         counter.Increment(Event{"john", "0.0.0.0"})
-//         fmt.Println("is unblocked", counter.Allow(Event{"john", "0.0.0.0"}))
-//
-//         counter.Increment(Event{"john", "0.0.0.0"})
-//         counter.Increment(Event{"john", "0.0.0.0"})
-//         counter.Increment(Event{"john", "0.0.0.0"})
-//
-//         fmt.Println("is unblocked", counter.Allow(Event{"john", "0.0.0.0"}))
-//         time.Sleep(2 * time.Second)
-//         fmt.Println("is unblocked", counter.Allow(Event{"john", "0.0.0.0"}))
-// }
+	_, ok := c.evictable(now)
+	return ok
+}
+
+// deleteExpired deletes at most limit expired keys. A negative limit deletes
+// all expired keys.
+func (c *Counter) deleteExpired(now time.Time, limit int) {
+	var i int
+	for _, it := range c.data {
+		if limit >= 0 && i >= limit {
+			break
+		}
+		if it.expired(now, c.policy.TTL) {
+			i++
+			c.expired++
+			c.remove(it)
+		}
+	}
+}
+
+func (c *Counter) clean(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	defer c.wg.Done()
+	for {
+		select {
+		case <-c.quit:
+			return
+		case <-ticker.C:
+			c.Lock()
+			// Clean at most 10,000 keys to prevent holding the lock for
+			// too long.
+			c.deleteExpired(time.Now(), 10_000)
+			c.Unlock()
+		}
+	}
+}
+
+func (c *Counter) cancel() {
+	c.Once.Do(func() {
+		close(c.quit)
+		c.wg.Wait()
+	})
+}
@@ -0,0 +1,166 @@
+package counter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// nowMillis reads the time of the redis server in milliseconds, so that all
+// instances evaluate the locks and the expiry with the same clock. The effects
+// of the script are replicated instead of the script, since TIME is not
+// deterministic.
+const nowMillis = `
+redis.replicate_commands()
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+`
+
+// The state of each key is stored in a hash, and the key expires once the
+// count and the lock level should be reset. The expiry is also checked in the
+// script, so the semantics do not depend on when redis evicts the key.
+var incrementScript = redis.NewScript(nowMillis + `
+-- KEYS[1]: The key to count, e.g. counter:login_failed:john.
+-- ARGV[1]: The max number of events before the key is locked.
+-- ARGV[2]: The ttl in milliseconds.
+-- ARGV[3...]: The lock duration in milliseconds for each level.
+local max = tonumber(ARGV[1])
+local ttl = tonumber(ARGV[2])
+
+local state = redis.call('HMGET', KEYS[1], 'value', 'level', 'last', 'locked')
+local value = tonumber(state[1]) or 0
+local level = tonumber(state[2]) or 0
+local last = tonumber(state[3]) or 0
+local locked = tonumber(state[4]) or 0
+
+if now - math.max(last, locked) > ttl then
+	value, level, last, locked = 0, 0, 0, 0
+end
+
+-- Events are not counted while the key is locked.
+if now < locked then
+	return 0
+end
+
+value = value + 1
+last = now
+if value >= max then
+	local levels = #ARGV - 2
+	locked = now + tonumber(ARGV[3 + math.min(level, levels - 1)])
+	level = level + 1
+	value = 0
+end
+
+redis.call('HMSET', KEYS[1], 'value', value, 'level', level, 'last', last, 'locked', locked)
+redis.call('PEXPIREAT', KEYS[1], math.max(last, locked) + ttl + 1)
+return 1
+`)
+
+// statusScript returns the state of the key, and the time of the redis server.
+var statusScript = redis.NewScript(nowMillis + `
+-- KEYS[1]: The key to count, e.g. counter:login_failed:john.
+local state = redis.call('HMGET', KEYS[1], 'value', 'level', 'last', 'locked')
+return {state[1], state[2], state[3], state[4], now}
+`)
+
+// Redis is a distributed counter with the same semantics as Counter, so that
+// the limits are shared across multiple instances.
+type Redis struct {
+	client *redis.Client
+	name   string
+	policy Policy
+}
+
+// NewRedis returns a new redis counter for the given policy. The name of the
+// policy is used to namespace the keys.
+func NewRedis(client *redis.Client, name string, policy Policy) *Redis {
+	return &Redis{
+		client: client,
+		name:   name,
+		policy: policy,
+	}
+}
+
+// Increment counts an event for the key, and locks the key once the count
+// reaches the policy's Max. Events are not counted while the key is locked.
+func (r *Redis) Increment(ctx context.Context, key string) error {
+	var (
+		keys = []string{r.key(key)}
+		args = []interface{}{
+			r.policy.Max,
+			r.policy.TTL.Milliseconds(),
+		}
+	)
+	if len(r.policy.Escalation) > 0 {
+		for _, d := range r.policy.Escalation {
+			args = append(args, d.Milliseconds())
+		}
+	} else {
+		args = append(args, r.policy.lockDuration(0).Milliseconds())
+	}
+	return incrementScript.Run(ctx, r.client, keys, args...).Err()
+}
+
+// Allow returns false while the key is locked.
+func (r *Redis) Allow(ctx context.Context, key string) (bool, error) {
+	s, err := r.Status(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return !s.Locked, nil
+}
+
+// Reset clears the count and the lock level of the key, e.g. after a
+// successful login.
+func (r *Redis) Reset(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.key(key)).Err()
+}
+
+// Status returns the current state of the key, evaluated with the time of
+// the redis server.
+func (r *Redis) Status(ctx context.Context, key string) (Status, error) {
+	res, err := statusScript.Run(ctx, r.client, []string{r.key(key)}).Result()
+	if err != nil {
+		return Status{}, err
+	}
+	state, ok := res.([]interface{})
+	if !ok || len(state) != 5 {
+		return Status{}, fmt.Errorf("counter: unexpected status reply %v", res)
+	}
+	if state[2] == nil {
+		return Status{}, nil
+	}
+
+	var values [4]int64
+	for i, v := range state[:4] {
+		s, _ := v.(string)
+		values[i], _ = strconv.ParseInt(s, 10, 64)
+	}
+	now, _ := state[4].(int64)
+
+	it := Item{
+		value:       int(values[0]),
+		level:       int(values[1]),
+		lastAccess:  fromMillis(values[2]),
+		lockedUntil: fromMillis(values[3]),
+	}
+
+	if it.expired(fromMillis(now), r.policy.TTL) {
+		return Status{}, nil
+	}
+	return it.status(fromMillis(now)), nil
+}
+
+func (r *Redis) key(key string) string {
+	return "counter:" + r.name + ":" + key
+}
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
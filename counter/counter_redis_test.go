@@ -0,0 +1,58 @@
+package counter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/alextanhongpin/pkg/counter"
+)
+
+func TestRedisConformance(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	testConformance(t, func(t *testing.T, policy counter.Policy) store {
+		// Each test uses its own namespace.
+		return redisStore{counter.NewRedis(client, t.Name(), policy)}
+	})
+}
+
+func TestRedisServerTime(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	// The clock of the redis server is behind the local clock, e.g. pods
+	// with clock skew. The locks follow the clock of the server.
+	now := time.Now().Add(-time.Hour)
+	s.SetTime(now)
+
+	store := redisStore{counter.NewRedis(client, t.Name(), counter.Policy{
+		Max:      1,
+		TTL:      time.Hour,
+		Cooldown: time.Minute,
+	})}
+	mustIncrement(t, store, "john")
+	status := assertStatus(t, store, "john", 0, 1, true)
+	if want := now.Add(time.Minute); status.UnlockAt.Sub(want).Abs() > time.Second {
+		t.Fatalf("expected unlock at %v, got %v", want, status.UnlockAt)
+	}
+	assertAllow(t, store, "john", false)
+
+	s.SetTime(now.Add(2 * time.Minute))
+	assertAllow(t, store, "john", true)
+}
@@ -0,0 +1,228 @@
+package counter_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/counter"
+)
+
+type Event struct {
+	Name     string
+	ClientIP string
+}
+
+func Example() {
+	c, cancel := counter.New(3, 100*time.Millisecond)
+	defer cancel()
+
+	evt := Event{"john", "0.0.0.0"}
+
+	c.Increment(evt)
+	fmt.Println("is unblocked", c.Allow(evt))
+
+	c.Increment(evt)
+	c.Increment(evt)
+	fmt.Println("is unblocked", c.Allow(evt))
+
+	time.Sleep(200 * time.Millisecond)
+	fmt.Println("is unblocked", c.Allow(evt))
+	// Output:
+	// is unblocked true
+	// is unblocked false
+	// is unblocked true
+}
+
+func TestRegistry(t *testing.T) {
+	r, cancel := counter.NewRegistry(map[string]counter.Policy{
+		"login_failed": {Max: 2, TTL: time.Minute},
+		"otp_sent":     {Max: 1, TTL: time.Minute, Cooldown: 50 * time.Millisecond},
+	})
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if err := r.Increment("login_failed", "john"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ok, _ := r.Allow("login_failed", "john"); ok {
+		t.Fatalf("expected login_failed to be blocked")
+	}
+
+	// Policies are counted separately.
+	if ok, _ := r.Allow("otp_sent", "john"); !ok {
+		t.Fatalf("expected otp_sent to be allowed")
+	}
+
+	if err := r.Increment("otp_sent", "john"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := r.Allow("otp_sent", "john"); ok {
+		t.Fatalf("expected otp_sent to be blocked")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if ok, _ := r.Allow("otp_sent", "john"); !ok {
+		t.Fatalf("expected otp_sent to be unblocked after cooldown")
+	}
+
+	if err := r.Increment("unknown", "john"); !errors.Is(err, counter.ErrPolicyNotFound) {
+		t.Fatalf("expected ErrPolicyNotFound, got %v", err)
+	}
+	if _, err := r.Allow("unknown", "john"); !errors.Is(err, counter.ErrPolicyNotFound) {
+		t.Fatalf("expected ErrPolicyNotFound, got %v", err)
+	}
+}
+
+func TestEscalation(t *testing.T) {
+	c, cancel := counter.NewWithPolicy(counter.Policy{
+		Max: 2,
+		TTL: time.Minute,
+		Escalation: []time.Duration{
+			50 * time.Millisecond,
+			100 * time.Millisecond,
+			time.Hour,
+		},
+	})
+	defer cancel()
+
+	fail := func(n int) {
+		for i := 0; i < n; i++ {
+			c.Increment("john")
+		}
+	}
+
+	for level, lock := range []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, time.Hour} {
+		fail(1)
+		if s := c.Status("john"); s.Count != 1 || s.Locked {
+			t.Fatalf("expected count 1 and unlocked, got %+v", s)
+		}
+
+		start := time.Now()
+		fail(2)
+		s := c.Status("john")
+		if !s.Locked || s.Level != level+1 || s.Count != 0 {
+			t.Fatalf("expected level %d lock, got %+v", level+1, s)
+		}
+		if d := s.UnlockAt.Sub(start); d < lock || d > lock+10*time.Millisecond {
+			t.Fatalf("expected lock of %v, got %v", lock, d)
+		}
+		if c.Allow("john") {
+			t.Fatalf("expected locked key to be disallowed")
+		}
+
+		// Events while locked are not counted.
+		fail(5)
+		if s := c.Status("john"); s.Count != 0 || s.Level != level+1 {
+			t.Fatalf("expected events to be ignored while locked, got %+v", s)
+		}
+
+		if lock < time.Hour {
+			time.Sleep(time.Until(s.UnlockAt) + 10*time.Millisecond)
+			if !c.Allow("john") {
+				t.Fatalf("expected key to be unlocked")
+			}
+		}
+	}
+
+	c.Reset("john")
+	if s := c.Status("john"); s != (counter.Status{}) {
+		t.Fatalf("expected status to be reset, got %+v", s)
+	}
+	if !c.Allow("john") {
+		t.Fatalf("expected reset key to be allowed")
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	c, cancel := counter.New(1, 10*time.Millisecond, counter.WithCleanupInterval(10*time.Millisecond))
+	defer cancel()
+
+	c.Increment("john")
+	c.Increment("jane")
+	if n := c.Len(); n != 2 {
+		t.Fatalf("expected 2 keys, got %d", n)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := c.Len(); n != 0 {
+		t.Fatalf("expected expired keys to be cleaned, got %d", n)
+	}
+	if s := c.Stats(); s.Expired != 2 {
+		t.Fatalf("expected 2 expired keys, got %+v", s)
+	}
+}
+
+func TestMaxKeys(t *testing.T) {
+	c, cancel := counter.New(2, time.Minute, counter.WithMaxKeys(2))
+	defer cancel()
+
+	// Lock john, so that it cannot be evicted.
+	c.Increment("john")
+	c.Increment("john")
+	c.Increment("jane")
+
+	// Evicts jane, the least recently used unlocked key.
+	c.Increment("bob")
+	if s := c.Status("jane"); s.Count != 0 {
+		t.Fatalf("expected jane to be evicted, got %+v", s)
+	}
+	if s := c.Status("john"); !s.Locked {
+		t.Fatalf("expected john to remain locked, got %+v", s)
+	}
+
+	// Lock bob, then there is no room for alice, who is not allowed until
+	// there is room again.
+	c.Increment("bob")
+	if err := c.Increment("alice"); !errors.Is(err, counter.ErrMaxKeys) {
+		t.Fatalf("expected ErrMaxKeys, got %v", err)
+	}
+	if s := c.Status("alice"); s.Count != 0 {
+		t.Fatalf("expected alice to be dropped, got %+v", s)
+	}
+	if c.Allow("alice") {
+		t.Fatal("expected untracked key to be denied at capacity")
+	}
+
+	s := c.Stats()
+	if s.Keys != 2 || s.Locked != 2 || s.Evicted != 1 || s.Dropped != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+
+	c.Reset("john")
+	if !c.Allow("alice") {
+		t.Fatal("expected untracked key to be allowed once there is room")
+	}
+}
+
+func TestMaxKeysLockEnded(t *testing.T) {
+	c, cancel := counter.NewWithPolicy(counter.Policy{
+		Max:      1,
+		TTL:      time.Minute,
+		Cooldown: 50 * time.Millisecond,
+	}, counter.WithMaxKeys(2))
+	defer cancel()
+
+	c.Increment("john")
+	c.Increment("jane")
+	if err := c.Increment("alice"); !errors.Is(err, counter.ErrMaxKeys) {
+		t.Fatalf("expected ErrMaxKeys, got %v", err)
+	}
+
+	// Once the locks end, the keys can be evicted again, starting from the
+	// lock that ended first.
+	time.Sleep(100 * time.Millisecond)
+	if err := c.Increment("alice"); err != nil {
+		t.Fatalf("expected alice to be tracked, got %v", err)
+	}
+	if s := c.Status("john"); s.Level != 0 {
+		t.Fatalf("expected john to be evicted, got %+v", s)
+	}
+	if s := c.Status("jane"); s.Level != 1 {
+		t.Fatalf("expected jane to remain, got %+v", s)
+	}
+	if s := c.Stats(); s.Keys != 2 || s.Evicted != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
@@ -0,0 +1,103 @@
+package counter
+
+import (
+	"container/heap"
+	"time"
+)
+
+// The tracked keys are either in the lru list, ordered by the last activity,
+// or in the locks heap, ordered by the end of the lock. Locked keys are never
+// evicted, so the least recently used unlocked key is at the back of the
+// list, and the keys whose lock has ended are at the top of the heap.
+
+// lockHeap is a min-heap of the locked items by the end of the lock.
+type lockHeap []*Item
+
+func (h lockHeap) Len() int           { return len(h) }
+func (h lockHeap) Less(i, j int) bool { return h[i].lockedUntil.Before(h[j].lockedUntil) }
+
+func (h lockHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lockHeap) Push(x interface{}) {
+	it := x.(*Item)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *lockHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	it.index = -1
+	*h = old[:n-1]
+	return it
+}
+
+// insert tracks a new item for the key.
+func (c *Counter) insert(key interface{}) *Item {
+	it := &Item{key: key, index: -1}
+	it.elem = c.lru.PushFront(it)
+	c.data[key] = it
+	return it
+}
+
+// remove stops tracking the item.
+func (c *Counter) remove(it *Item) {
+	if it.elem != nil {
+		c.lru.Remove(it.elem)
+		it.elem = nil
+	}
+	if it.index >= 0 {
+		heap.Remove(&c.locks, it.index)
+	}
+	delete(c.data, it.key)
+}
+
+// touch moves the item to the lock heap if it is locked, otherwise to the
+// front of the lru list.
+func (c *Counter) touch(it *Item, now time.Time) {
+	if now.Before(it.lockedUntil) {
+		if it.elem != nil {
+			c.lru.Remove(it.elem)
+			it.elem = nil
+		}
+		if it.index >= 0 {
+			heap.Fix(&c.locks, it.index)
+		} else {
+			heap.Push(&c.locks, it)
+		}
+		return
+	}
+	if it.index >= 0 {
+		heap.Remove(&c.locks, it.index)
+	}
+	if it.elem != nil {
+		c.lru.MoveToFront(it.elem)
+	} else {
+		it.elem = c.lru.PushFront(it)
+	}
+}
+
+// unlock moves the items whose lock has ended back to the lru list. The end
+// of the lock counts as the last activity.
+func (c *Counter) unlock(now time.Time) {
+	for len(c.locks) > 0 && !now.Before(c.locks[0].lockedUntil) {
+		it := heap.Pop(&c.locks).(*Item)
+		it.elem = c.lru.PushFront(it)
+	}
+}
+
+// evictable returns the least recently used unlocked item, if any.
+func (c *Counter) evictable(now time.Time) (*Item, bool) {
+	c.unlock(now)
+	e := c.lru.Back()
+	if e == nil {
+		return nil, false
+	}
+	return e.Value.(*Item), true
+}
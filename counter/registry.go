@@ -0,0 +1,107 @@
+package counter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPolicyNotFound is returned when the policy is not registered.
+var ErrPolicyNotFound = errors.New("counter: policy not found")
+
+// Registry holds a counter for each named policy, e.g. "login_failed" and
+// "otp_sent", so that the limits for every flow are kept in one place.
+type Registry struct {
+	sync.RWMutex
+	counters map[string]*Counter
+	cancels  map[string]func()
+	opts     []Option
+}
+
+// NewRegistry returns a new registry with the given policies, and a function
+// to stop the cleanup of expired keys for all policies. The options apply to
+// the counter of every policy.
+func NewRegistry(policies map[string]Policy, opts ...Option) (*Registry, func()) {
+	r := &Registry{
+		counters: make(map[string]*Counter),
+		cancels:  make(map[string]func()),
+		opts:     opts,
+	}
+	for name, policy := range policies {
+		r.Register(name, policy)
+	}
+	return r, r.cancel
+}
+
+// Register adds a new policy, replacing the existing policy and counts with
+// the same name.
+func (r *Registry) Register(name string, policy Policy) {
+	c, cancel := NewWithPolicy(policy, r.opts...)
+
+	r.Lock()
+	prev, ok := r.cancels[name]
+	r.counters[name] = c
+	r.cancels[name] = cancel
+	r.Unlock()
+
+	if ok {
+		prev()
+	}
+}
+
+// Counter returns the counter for the given policy.
+func (r *Registry) Counter(policy string) (*Counter, error) {
+	r.RLock()
+	c, ok := r.counters[policy]
+	r.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrPolicyNotFound, policy)
+	}
+	return c, nil
+}
+
+// Increment increments the count of the key for the given policy.
+func (r *Registry) Increment(policy string, key interface{}) error {
+	c, err := r.Counter(policy)
+	if err != nil {
+		return err
+	}
+	return c.Increment(key)
+}
+
+// Allow checks if the key is allowed for the given policy.
+func (r *Registry) Allow(policy string, key interface{}) (bool, error) {
+	c, err := r.Counter(policy)
+	if err != nil {
+		return false, err
+	}
+	return c.Allow(key), nil
+}
+
+// Reset clears the count and the lock level of the key for the given policy.
+func (r *Registry) Reset(policy string, key interface{}) error {
+	c, err := r.Counter(policy)
+	if err != nil {
+		return err
+	}
+	c.Reset(key)
+	return nil
+}
+
+// Status returns the current state of the key for the given policy.
+func (r *Registry) Status(policy string, key interface{}) (Status, error) {
+	c, err := r.Counter(policy)
+	if err != nil {
+		return Status{}, err
+	}
+	return c.Status(key), nil
+}
+
+func (r *Registry) cancel() {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+}
@@ -0,0 +1,129 @@
+package gojwt
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// RegisteredClaims are the registered claims of RFC 7519, to be embedded in
+// the custom claims types of TypedSigner.
+type RegisteredClaims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+}
+
+// StandardClaims are the registered claims of the default Claims, with the
+// same fields as the StandardClaims of jwt-go. The aud claim must be a single
+// string, use RegisteredClaims to support multiple audiences.
+type StandardClaims struct {
+	Audience  string `json:"aud,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	Id        string `json:"jti,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+}
+
+// VerifyAudience compares the aud claim. If required is false, a missing aud
+// claim is accepted.
+func (c *StandardClaims) VerifyAudience(cmp string, required bool) bool {
+	return verifyString(c.Audience, cmp, required)
+}
+
+// VerifyIssuer compares the iss claim. If required is false, a missing iss
+// claim is accepted.
+func (c *StandardClaims) VerifyIssuer(cmp string, required bool) bool {
+	return verifyString(c.Issuer, cmp, required)
+}
+
+// VerifyExpiresAt checks that the exp claim is not before cmp, in unix
+// seconds. If required is false, a missing exp claim is accepted.
+func (c *StandardClaims) VerifyExpiresAt(cmp int64, required bool) bool {
+	if c.ExpiresAt == 0 {
+		return !required
+	}
+	return cmp <= c.ExpiresAt
+}
+
+// VerifyIssuedAt checks that the iat claim is not after cmp, in unix seconds.
+// If required is false, a missing iat claim is accepted.
+func (c *StandardClaims) VerifyIssuedAt(cmp int64, required bool) bool {
+	if c.IssuedAt == 0 {
+		return !required
+	}
+	return cmp >= c.IssuedAt
+}
+
+// VerifyNotBefore checks that the nbf claim is not after cmp, in unix
+// seconds. If required is false, a missing nbf claim is accepted.
+func (c *StandardClaims) VerifyNotBefore(cmp int64, required bool) bool {
+	if c.NotBefore == 0 {
+		return !required
+	}
+	return cmp >= c.NotBefore
+}
+
+func verifyString(value, cmp string, required bool) bool {
+	if value == "" {
+		return !required
+	}
+	return subtle.ConstantTimeCompare([]byte(value), []byte(cmp)) == 1
+}
+
+// Audience is the aud claim, which is either a single string or an array of
+// strings.
+type Audience []string
+
+// MarshalJSON encodes a single audience as a string.
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON decodes either a string or an array of strings.
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+// encodeClaims encodes the claims, and sets the exp and iat claims. The jti
+// claim is set to the id if it is empty.
+func encodeClaims(claims interface{}, expiresAt, issuedAt time.Time, id string) ([]byte, error) {
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil || m == nil {
+		return nil, errors.New("gojwt: claims must be a JSON object")
+	}
+	m["exp"] = json.RawMessage(strconv.FormatInt(expiresAt.Unix(), 10))
+	m["iat"] = json.RawMessage(strconv.FormatInt(issuedAt.Unix(), 10))
+	if jti, ok := m["jti"]; id != "" && (!ok || string(jti) == `""`) {
+		m["jti"], _ = json.Marshal(id)
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,111 @@
+package gojwt_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/gojwt"
+)
+
+type ServiceClaims struct {
+	gojwt.RegisteredClaims
+	Scopes []string `json:"scope"`
+}
+
+var _ gojwt.Signer[ServiceClaims] = gojwt.NewTyped(gojwt.TypedOption[ServiceClaims]{})
+var _ gojwt.Signer[gojwt.Claims] = gojwt.New(gojwt.Option{})
+
+func TestTypedSigner(t *testing.T) {
+	signer := gojwt.NewTyped(gojwt.TypedOption[ServiceClaims]{
+		Secret:       []byte("secret"),
+		ExpiresAfter: 10 * time.Second,
+		DefaultClaims: &ServiceClaims{
+			RegisteredClaims: gojwt.RegisteredClaims{
+				Issuer:   "auth",
+				Audience: gojwt.Audience{"billing", "payments"},
+			},
+		},
+		Audiences: []string{"payments"},
+		Validator: func(c *ServiceClaims) error {
+			if len(c.Scopes) == 0 {
+				return errors.New("missing scope")
+			}
+			return nil
+		},
+	})
+	token, err := signer.Sign(func(c *ServiceClaims) error {
+		c.Subject = "orders"
+		c.Scopes = []string{"payments:read"}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := payload["aud"].([]interface{}); !ok {
+		t.Fatalf("expected aud array, got %v", payload["aud"])
+	}
+
+	claims, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("verify token failed: %v", err)
+	}
+	if claims.Subject != "orders" || claims.Issuer != "auth" {
+		t.Fatalf("expected registered claims, got %+v", claims.RegisteredClaims)
+	}
+	if !reflect.DeepEqual(claims.Scopes, []string{"payments:read"}) {
+		t.Fatalf("expected scopes, got %v", claims.Scopes)
+	}
+	if claims.ExpiresAt-claims.IssuedAt != 10 {
+		t.Fatalf("expected exp 10s after iat, got %d and %d", claims.ExpiresAt, claims.IssuedAt)
+	}
+
+	token, err = signer.Sign(func(c *ServiceClaims) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if _, err := signer.Verify(token); err == nil || err.Error() != "missing scope" {
+		t.Fatalf("expected validator error, got %v", err)
+	}
+}
+
+func TestAudienceJSON(t *testing.T) {
+	tests := []struct {
+		aud  gojwt.Audience
+		json string
+	}{
+		{gojwt.Audience{"a"}, `"a"`},
+		{gojwt.Audience{"a", "b"}, `["a","b"]`},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.aud)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.json {
+			t.Fatalf("expected %s, got %s", tt.json, b)
+		}
+		var aud gojwt.Audience
+		if err := json.Unmarshal(b, &aud); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(aud, tt.aud) {
+			t.Fatalf("expected %v, got %v", tt.aud, aud)
+		}
+	}
+}
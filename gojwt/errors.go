@@ -0,0 +1,89 @@
+package gojwt
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnsupportedAlgorithm is returned when the algorithm is not one of the
+	// supported signing algorithms, or does not match the key type.
+	ErrUnsupportedAlgorithm = errors.New("gojwt: unsupported algorithm")
+
+	// ErrUnknownKey is returned when there is no verification key for the kid
+	// of the token.
+	ErrUnknownKey = errors.New("gojwt: unknown key")
+
+	// ErrMissingKey is returned when the signing key for the algorithm is
+	// not configured.
+	ErrMissingKey = errors.New("gojwt: missing key")
+
+	// ErrInvalidKey is returned when the key type does not match the
+	// algorithm.
+	ErrInvalidKey = errors.New("gojwt: invalid key type")
+
+	// ErrMalformedToken is returned when the token cannot be decoded.
+	ErrMalformedToken = errors.New("gojwt: malformed token")
+
+	// ErrDecryptionFailed is returned when the JWE cannot be decrypted or
+	// authenticated.
+	ErrDecryptionFailed = errors.New("gojwt: decryption failed")
+
+	// ErrAlgorithmNotAllowed is returned when the alg header of the token is
+	// not one of the Option.Algorithms.
+	ErrAlgorithmNotAllowed = errors.New("gojwt: algorithm not allowed")
+
+	// ErrInvalidSignature is returned when the signature does not match.
+	ErrInvalidSignature = errors.New("gojwt: invalid signature")
+
+	// ErrTokenExpired is returned when the exp claim is in the past.
+	ErrTokenExpired = errors.New("gojwt: token expired")
+
+	// ErrTokenNotYetValid is returned when the nbf or iat claim is in the
+	// future.
+	ErrTokenNotYetValid = errors.New("gojwt: token not yet valid")
+
+	// ErrInvalidIssuer is returned when the iss claim is not one of the
+	// Option.Issuers.
+	ErrInvalidIssuer = errors.New("gojwt: invalid issuer")
+
+	// ErrInvalidAudience is returned when none of the aud claim values is one
+	// of the Option.Audiences.
+	ErrInvalidAudience = errors.New("gojwt: invalid audience")
+
+	// ErrTokenRevoked is returned when the jti claim, or the family of the
+	// refresh token, has been revoked.
+	ErrTokenRevoked = errors.New("gojwt: token revoked")
+
+	// ErrTokenReused is returned when a refresh token is used more than
+	// once. The whole token family is revoked, since either the legitimate
+	// client or an attacker holds a stolen token.
+	ErrTokenReused = errors.New("gojwt: refresh token reused")
+
+	// ErrMissingRevocationStore is returned when revoking tokens without
+	// the RevocationStore.
+	ErrMissingRevocationStore = errors.New("gojwt: missing revocation store")
+
+	// ErrUnavailable is returned when the token cannot be verified because
+	// the KeySet or the RevocationStore failed, e.g. the JWKS URL or the
+	// store is down. The token itself may be valid.
+	ErrUnavailable = errors.New("gojwt: verification unavailable")
+
+	// ErrMissingClaim is returned when one of the Option.RequiredClaims is
+	// not present.
+	ErrMissingClaim = errors.New("gojwt: missing claim")
+)
+
+// ClaimError describes the claim that failed the validation.
+type ClaimError struct {
+	Claim string
+	Err   error
+}
+
+func (e *ClaimError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err, e.Claim)
+}
+
+func (e *ClaimError) Unwrap() error {
+	return e.Err
+}
@@ -1,11 +1,12 @@
 package gojwt
 
 import (
+	"context"
+	"crypto"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
-
-	"github.com/dgrijalva/jwt-go"
 )
 
 type (
@@ -22,31 +23,87 @@ type (
 
 	Claims struct {
 		UserInfo
-		jwt.StandardClaims
+		StandardClaims
 	}
 
 	Validator func(*Claims) error
 
 	NowFunc func() time.Time
 
-	// Option can be configured based on your needs.
-	Option struct {
+	// Option configures the JwtSigner with the default Claims.
+	Option = TypedOption[Claims]
+
+	// TypedOption can be configured based on your needs. C is the claims
+	// type, which usually embeds the RegisteredClaims.
+	TypedOption[C any] struct {
 		Secret        []byte
 		ExpiresAfter  time.Duration
-		DefaultClaims *Claims
-		Validator     Validator
+		DefaultClaims *C
+		Validator     func(*C) error
 		NowFunc       NowFunc
+
+		// Algorithm is the signing algorithm, one of HS256, RS256, ES256 or
+		// EdDSA. Defaults to the algorithm of the PrivateKey, or HS256 with
+		// the Secret.
+		Algorithm string
+
+		// PrivateKey signs the tokens with the asymmetric algorithms, e.g.
+		// *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey. The
+		// public key is used for verification and published in the JWKS.
+		PrivateKey crypto.Signer
+
+		// KeyID is set as the kid header of the signed tokens.
+		KeyID string
+
+		// PublicKeys are additional verification keys by kid, e.g. the
+		// previous keys during key rotation.
+		PublicKeys map[string]crypto.PublicKey
+
+		// KeySet looks up the verification keys that are not found in the
+		// PublicKeys, e.g. a RemoteKeySet of the identity provider.
+		KeySet KeySet
+
+		// Algorithms are the allowed alg headers of the verified tokens.
+		// Defaults to the Algorithm, the algorithms of the PublicKeys, and
+		// the asymmetric algorithms if the KeySet is set.
+		Algorithms []string
+
+		// Issuers are the accepted iss claims. Any issuer is accepted if
+		// empty.
+		Issuers []string
+
+		// Audiences are the accepted aud claims. The token is valid if any
+		// of its audiences is accepted. Any audience is accepted if empty.
+		Audiences []string
+
+		// Leeway is the tolerance for the exp, nbf and iat claims, since the
+		// clocks of the issuer and the verifier may drift.
+		Leeway time.Duration
+
+		// RequiredClaims are the claims that must be present, e.g. sub.
+		RequiredClaims []string
+
+		// RevocationStore rejects the revoked tokens on Verify, see Revoke.
+		// Sign sets a random jti claim if the claims do not have one.
+		RevocationStore RevocationStore
+
+		// Encryption encrypts the signed tokens as JWE. Verify then only
+		// accepts the encrypted tokens.
+		Encryption *Encryption
 	}
 
-	// Signer represents the JwtSigner operations.
-	Signer interface {
-		Sign(func(*Claims) error) (string, error)
-		Verify(token string) (*Claims, error)
+	// Signer represents the JwtSigner operations for the claims type C.
+	Signer[C any] interface {
+		Sign(func(*C) error) (string, error)
+		Verify(token string) (*C, error)
 	}
 
-	// JwtSigner ...
-	JwtSigner struct {
-		opt Option
+	// JwtSigner signs and verifies the default Claims.
+	JwtSigner = TypedSigner[Claims]
+
+	// TypedSigner signs and verifies the claims type C.
+	TypedSigner[C any] struct {
+		opt TypedOption[C]
 	}
 )
 
@@ -65,66 +122,241 @@ func DefaultValidator(*Claims) error {
 
 // New returns a new jwt signer.
 func New(opt Option) *JwtSigner {
+	return NewTyped(opt)
+}
+
+// NewTyped returns a new jwt signer for the claims type C, e.g.
+//
+//	type ServiceClaims struct {
+//		gojwt.RegisteredClaims
+//		Scopes []string `json:"scope"`
+//	}
+//
+//	signer := gojwt.NewTyped(gojwt.TypedOption[ServiceClaims]{...})
+func NewTyped[C any](opt TypedOption[C]) *TypedSigner[C] {
 	if opt.DefaultClaims == nil {
-		opt.DefaultClaims = &Claims{}
+		opt.DefaultClaims = new(C)
 	}
 	if opt.Validator == nil {
-		opt.Validator = DefaultValidator
+		opt.Validator = func(*C) error {
+			return nil
+		}
 	}
 	if opt.NowFunc == nil {
 		opt.NowFunc = DefaultNowFunc
 	}
-	return &JwtSigner{opt}
+	if opt.Algorithm == "" {
+		opt.Algorithm = HS256
+		if opt.PrivateKey != nil {
+			// Invalid key types are reported on Sign.
+			opt.Algorithm, _ = algorithmOf(opt.PrivateKey.Public())
+		}
+	}
+	if len(opt.Algorithms) == 0 {
+		opt.Algorithms = defaultAlgorithms(opt.Algorithm, opt.PublicKeys, opt.KeySet)
+	}
+	if opt.Encryption != nil {
+		enc := *opt.Encryption
+		if enc.ContentEncryption == "" {
+			enc.ContentEncryption = A256GCM
+		}
+		if enc.DecryptionKey == nil && enc.Algorithm == Dir {
+			enc.DecryptionKey = enc.Key
+		}
+		opt.Encryption = &enc
+	}
+	return &TypedSigner[C]{opt}
 }
 
 // Sign takes a function that modifies the claims and return a signed token
 // string.
-func (j *JwtSigner) Sign(fn func(c *Claims) error) (string, error) {
+func (j *TypedSigner[C]) Sign(fn func(c *C) error) (string, error) {
 	var (
 		claims       = *j.opt.DefaultClaims
 		expiresAfter = j.opt.ExpiresAfter
 		now          = j.opt.NowFunc()
-		secret       = j.opt.Secret
 	)
-	err := fn(&claims)
+	key, err := j.signingKey()
 	if err != nil {
 		return "", fmt.Errorf("sign token failed: %w", err)
 	}
+	if err := fn(&claims); err != nil {
+		return "", fmt.Errorf("sign token failed: %w", err)
+	}
 	// Set the expires at and issued at time.
-	claims.ExpiresAt = now.Add(expiresAfter).Unix()
-	claims.IssuedAt = now.Unix()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString(secret)
+	var id string
+	if j.opt.RevocationStore != nil {
+		id = newID()
+	}
+	payload, err := encodeClaims(claims, now.Add(expiresAfter), now, id)
 	if err != nil {
 		return "", fmt.Errorf("sign token failed: %w", err)
 	}
+	header := Header{
+		Alg: j.opt.Algorithm,
+		Kid: j.opt.KeyID,
+		Typ: "JWT",
+	}
+	ss, err := SignJWS(header, key, payload)
+	if err != nil {
+		return "", fmt.Errorf("sign token failed: %w", err)
+	}
+	if j.opt.Encryption != nil {
+		ss, err = j.opt.Encryption.encrypt(ss)
+		if err != nil {
+			return "", fmt.Errorf("encrypt token failed: %w", err)
+		}
+	}
 	return ss, nil
 }
 
 // Verify checks if the given token string is valid, and returns the claims or
 // error.
-func (j *JwtSigner) Verify(tokenString string) (*Claims, error) {
+func (j *TypedSigner[C]) Verify(tokenString string) (*C, error) {
+	return j.VerifyContext(context.Background(), tokenString)
+}
+
+// VerifyContext is like Verify, but passes the context to the KeySet and the
+// RevocationStore.
+func (j *TypedSigner[C]) VerifyContext(ctx context.Context, tokenString string) (*C, error) {
+	payload, _, err := j.verify(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	var claims C
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("invalid authorization header: %w: %s", ErrMalformedToken, err)
+	}
+	return &claims, j.opt.Validator(&claims)
+}
+
+// Revoke revokes the token until it expires, e.g. on logout. Verify rejects
+// the revoked tokens with ErrTokenRevoked. Requires the RevocationStore, and
+// the jti claim, which is set by Sign when the RevocationStore is configured.
+func (j *TypedSigner[C]) Revoke(ctx context.Context, tokenString string) error {
+	if j.opt.RevocationStore == nil {
+		return ErrMissingRevocationStore
+	}
+	_, c, err := j.verify(ctx, tokenString)
+	if err != nil {
+		return err
+	}
+	if c.ID == "" {
+		return &ClaimError{Claim: "jti", Err: ErrMissingClaim}
+	}
+	expiresAt := j.opt.NowFunc().Add(j.opt.ExpiresAfter)
+	if c.ExpiresAt != nil {
+		expiresAt = unixTime(*c.ExpiresAt)
+	}
+	_, err = j.opt.RevocationStore.Revoke(ctx, c.ID, j.remaining(expiresAt))
+	return err
+}
+
+// verify verifies the signature, the registered claims, and whether the token
+// has been revoked, and returns the decoded payload.
+func (j *TypedSigner[C]) verify(ctx context.Context, tokenString string) ([]byte, *registeredClaims, error) {
 	if tokenString == "" {
-		return nil, ErrEmpty
-	}
-	token, err := jwt.ParseWithClaims(
-		tokenString,
-		&Claims{},
-		func(token *jwt.Token) (interface{}, error) {
-			return j.opt.Secret, nil
-		},
-	)
+		return nil, nil, ErrEmpty
+	}
+	if j.opt.Encryption != nil {
+		var err error
+		tokenString, err = j.opt.Encryption.decrypt(tokenString)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid authorization header: %w", err)
+		}
+	}
+	token, err := ParseJWS(tokenString)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid authorization header: %w", err)
+	}
+	key, err := j.verificationKey(ctx, token.Header)
 	if err != nil {
-		return nil, fmt.Errorf("invalid authorization header: %w", err)
+		return nil, nil, fmt.Errorf("invalid authorization header: %w", err)
+	}
+	if err := token.Verify(key); err != nil {
+		return nil, nil, fmt.Errorf("invalid authorization header: %w", err)
+	}
+	c, err := j.validateClaims(token.Payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid authorization header: %w", err)
+	}
+	if j.opt.RevocationStore != nil && c.ID != "" {
+		revoked, err := j.opt.RevocationStore.Revoked(ctx, c.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		if revoked {
+			return nil, nil, fmt.Errorf("invalid authorization header: %w", ErrTokenRevoked)
+		}
 	}
+	return token.Payload, c, nil
+}
+
+// remaining returns how long the token must be remembered, until it can no
+// longer pass the validation of the exp claim.
+func (j *TypedSigner[C]) remaining(expiresAt time.Time) time.Duration {
+	// The exp claim is compared in seconds.
+	ttl := expiresAt.Sub(j.opt.NowFunc()) + j.opt.Leeway + time.Second
+	if ttl < time.Second {
+		// A non-positive ttl never expires in the stores.
+		return time.Second
+	}
+	return ttl
+}
+
+func (j *TypedSigner[C]) signingKey() (interface{}, error) {
+	if j.opt.Algorithm == HS256 {
+		if len(j.opt.Secret) == 0 {
+			return nil, ErrMissingKey
+		}
+		return j.opt.Secret, nil
+	}
+	if j.opt.PrivateKey == nil {
+		return nil, ErrMissingKey
+	}
+	return j.opt.PrivateKey, nil
+}
 
-	// Apparently this is possible by sending Authorization: Bearer
-	// undefined.
-	if token == nil {
-		return nil, errors.New("invalid authorization header")
+// verificationKey returns the key for the algorithm and kid of the token.
+// Tokens without kid are verified with the signing key.
+func (j *TypedSigner[C]) verificationKey(ctx context.Context, header Header) (interface{}, error) {
+	alg := header.Alg
+	if err := j.allowAlgorithm(alg); err != nil {
+		return nil, err
+	}
+	if alg == HS256 {
+		// An empty secret would allow anyone to sign the token, e.g. on
+		// verifiers that only use the KeySet.
+		if len(j.opt.Secret) == 0 {
+			return nil, ErrMissingKey
+		}
+		return j.opt.Secret, nil
+	}
+	kid := header.Kid
+	pub, err := j.publicKey(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	// Each key may only be used with its own algorithm.
+	if keyAlg, err := algorithmOf(pub); err != nil || keyAlg != alg {
+		return nil, fmt.Errorf(`%w: "%s" for kid "%s"`, ErrUnsupportedAlgorithm, alg, kid)
+	}
+	return pub, nil
+}
+
+func (j *TypedSigner[C]) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if j.opt.PrivateKey != nil && kid == j.opt.KeyID {
+		return j.opt.PrivateKey.Public(), nil
+	}
+	if pub, ok := j.opt.PublicKeys[kid]; ok {
+		return pub, nil
 	}
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, j.opt.Validator(claims)
+	if j.opt.KeySet != nil {
+		pub, err := j.opt.KeySet.PublicKey(ctx, kid)
+		if err != nil && !errors.Is(err, ErrUnknownKey) {
+			return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		return pub, err
 	}
-	return nil, err
+	return nil, fmt.Errorf(`%w: "%s"`, ErrUnknownKey, kid)
 }
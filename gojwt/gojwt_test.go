@@ -9,7 +9,6 @@ import (
 	"errors"
 
 	"github.com/alextanhongpin/pkg/gojwt"
-	"github.com/dgrijalva/jwt-go"
 )
 
 func Example() {
@@ -23,7 +22,7 @@ func Example() {
 		Secret:       []byte(secret),
 		ExpiresAfter: expiresAfter,
 		DefaultClaims: &gojwt.Claims{
-			StandardClaims: jwt.StandardClaims{
+			StandardClaims: gojwt.StandardClaims{
 				Audience: audience,
 				Issuer:   issuer,
 			},
@@ -64,7 +63,7 @@ func TestSignAndVerify(t *testing.T) {
 			Secret:       []byte(secret),
 			ExpiresAfter: expiresAfter,
 			DefaultClaims: &gojwt.Claims{
-				StandardClaims: jwt.StandardClaims{
+				StandardClaims: gojwt.StandardClaims{
 					Audience: audience,
 					Issuer:   issuer,
 				},
@@ -94,7 +93,7 @@ func TestDifferentAudience(t *testing.T) {
 			Secret:       []byte("secret"),
 			ExpiresAfter: 10 * time.Second,
 			DefaultClaims: &gojwt.Claims{
-				StandardClaims: jwt.StandardClaims{
+				StandardClaims: gojwt.StandardClaims{
 					Audience: "audience 1",
 					Issuer:   "issuer",
 				},
@@ -107,7 +106,7 @@ func TestDifferentAudience(t *testing.T) {
 			Secret:       []byte("secret"),
 			ExpiresAfter: 10 * time.Second,
 			DefaultClaims: &gojwt.Claims{
-				StandardClaims: jwt.StandardClaims{
+				StandardClaims: gojwt.StandardClaims{
 					Audience: "audience 2",
 					Issuer:   "issuer",
 				},
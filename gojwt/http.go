@@ -0,0 +1,163 @@
+package gojwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alextanhongpin/pkg/authhdr"
+)
+
+type contextKey string
+
+const claimsContextKey = contextKey("gojwt_claims")
+
+// WithClaims populates the context with the verified claims.
+func WithClaims[C any](ctx context.Context, claims *C) context.Context {
+	return context.WithValue(ctx, claimsContextKey, claims)
+}
+
+// ClaimsFromContext extracts the verified claims from the context. C must be
+// the claims type of the TypedSigner, e.g. Claims for New.
+func ClaimsFromContext[C any](ctx context.Context) (*C, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*C)
+	return claims, ok
+}
+
+// MiddlewareOption configures the Middleware.
+type MiddlewareOption func(*middleware)
+
+type middleware struct {
+	realm  string
+	cookie string
+	query  string
+}
+
+// WithRealm sets the realm of the WWW-Authenticate header.
+func WithRealm(realm string) MiddlewareOption {
+	return func(m *middleware) {
+		m.realm = realm
+	}
+}
+
+// WithCookie also reads the token from the cookie, e.g. for browsers.
+func WithCookie(name string) MiddlewareOption {
+	return func(m *middleware) {
+		m.cookie = name
+	}
+}
+
+// WithQuery also reads the token from the query parameter, usually
+// access_token. Avoid it if possible, since the URLs are often logged.
+func WithQuery(name string) MiddlewareOption {
+	return func(m *middleware) {
+		m.query = name
+	}
+}
+
+// errInvalidRequest is returned when the token is sent in a malformed
+// Authorization header, or with more than one method.
+var errInvalidRequest = errors.New("gojwt: invalid request")
+
+// Middleware verifies the bearer token of the requests, and populates the
+// request context with the claims, see ClaimsFromContext. The token is read
+// from the Authorization header, and optionally the cookie or the query
+// parameter. Failures are rejected with the WWW-Authenticate header of RFC
+// 6750, except ErrUnavailable, which is rejected with 503 Service
+// Unavailable.
+func (j *TypedSigner[C]) Middleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
+	m := &middleware{}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := m.extract(r)
+		if err != nil {
+			m.error(w, http.StatusBadRequest, "invalid_request", "The request is malformed")
+			return
+		}
+		if token == "" {
+			m.error(w, http.StatusUnauthorized, "", "")
+			return
+		}
+		claims, err := j.VerifyContext(r.Context(), token)
+		if errors.Is(err, ErrUnavailable) {
+			// The token is not at fault, so the client may retry.
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			m.error(w, http.StatusUnauthorized, "invalid_token", describe(err))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+	})
+}
+
+// extract returns the token, or an empty string if there is none. Clients
+// must not use more than one method to send the token (RFC 6750 2).
+func (m *middleware) extract(r *http.Request) (string, error) {
+	var tokens []string
+	if r.Header.Get("Authorization") != "" {
+		hdr := authhdr.New()
+		if err := hdr.Extract(r); err != nil {
+			return "", errInvalidRequest
+		}
+		// Other schemes, e.g. Basic, are not meant for this middleware.
+		if hdr.BearerIs(authhdr.Bearer) {
+			tokens = append(tokens, hdr.Token())
+		}
+	}
+	if m.cookie != "" {
+		if c, err := r.Cookie(m.cookie); err == nil && c.Value != "" {
+			tokens = append(tokens, c.Value)
+		}
+	}
+	if m.query != "" {
+		if v := r.URL.Query().Get(m.query); v != "" {
+			tokens = append(tokens, v)
+		}
+	}
+	switch len(tokens) {
+	case 0:
+		return "", nil
+	case 1:
+		return tokens[0], nil
+	default:
+		return "", errInvalidRequest
+	}
+}
+
+func (m *middleware) error(w http.ResponseWriter, code int, errorCode, description string) {
+	params := []string{fmt.Sprintf("realm=%q", m.realm)}
+	if m.realm == "" {
+		params = params[:0]
+	}
+	if errorCode != "" {
+		params = append(params, fmt.Sprintf("error=%q", errorCode))
+	}
+	if description != "" {
+		params = append(params, fmt.Sprintf("error_description=%q", description))
+	}
+	challenge := "Bearer"
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(code), code)
+}
+
+// describe returns the error description without the details of the
+// verification.
+func describe(err error) string {
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		return "The access token expired"
+	case errors.Is(err, ErrTokenRevoked):
+		return "The access token has been revoked"
+	default:
+		return "The access token is invalid"
+	}
+}
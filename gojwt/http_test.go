@@ -0,0 +1,190 @@
+package gojwt_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/gojwt"
+)
+
+func TestMiddleware(t *testing.T) {
+	signer := gojwt.New(gojwt.Option{
+		Secret:       []byte("secret"),
+		ExpiresAfter: time.Minute,
+	})
+	expired := gojwt.New(gojwt.Option{
+		Secret:       []byte("secret"),
+		ExpiresAfter: -time.Minute,
+	})
+	token := signToken(t, signer)
+
+	handler := signer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := gojwt.ClaimsFromContext[gojwt.Claims](r.Context())
+		if !ok {
+			t.Fatal("expected claims in context")
+		}
+		fmt.Fprint(w, claims.Subject)
+	}), gojwt.WithRealm("api"), gojwt.WithCookie("access_token"), gojwt.WithQuery("access_token"))
+
+	tests := []struct {
+		name    string
+		request func(r *http.Request)
+		code    int
+		auth    string
+	}{
+		{
+			name: "header",
+			request: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+token)
+			},
+			code: http.StatusOK,
+		},
+		{
+			name: "cookie",
+			request: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			},
+			code: http.StatusOK,
+		},
+		{
+			name: "query",
+			request: func(r *http.Request) {
+				r.URL.RawQuery = "access_token=" + token
+			},
+			code: http.StatusOK,
+		},
+		{
+			name:    "missing",
+			request: func(r *http.Request) {},
+			code:    http.StatusUnauthorized,
+			auth:    `Bearer realm="api"`,
+		},
+		{
+			name: "other scheme",
+			request: func(r *http.Request) {
+				r.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+			},
+			code: http.StatusUnauthorized,
+			auth: `Bearer realm="api"`,
+		},
+		{
+			name: "malformed header",
+			request: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer")
+			},
+			code: http.StatusBadRequest,
+			auth: `Bearer realm="api", error="invalid_request", error_description="The request is malformed"`,
+		},
+		{
+			name: "multiple methods",
+			request: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+token)
+				r.URL.RawQuery = "access_token=" + token
+			},
+			code: http.StatusBadRequest,
+			auth: `Bearer realm="api", error="invalid_request", error_description="The request is malformed"`,
+		},
+		{
+			name: "invalid token",
+			request: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+token+"x")
+			},
+			code: http.StatusUnauthorized,
+			auth: `Bearer realm="api", error="invalid_token", error_description="The access token is invalid"`,
+		},
+		{
+			name: "expired token",
+			request: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+signToken(t, expired))
+			},
+			code: http.StatusUnauthorized,
+			auth: `Bearer realm="api", error="invalid_token", error_description="The access token expired"`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.request(r)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+			if rr.Code != tt.code {
+				t.Fatalf("expected status %d, got %d", tt.code, rr.Code)
+			}
+			if auth := rr.Header().Get("WWW-Authenticate"); auth != tt.auth {
+				t.Fatalf("expected WWW-Authenticate %q, got %q", tt.auth, auth)
+			}
+			if tt.code == http.StatusOK && rr.Body.String() != "user 1" {
+				t.Fatalf("expected %s, got %s", "user 1", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestClaimsFromContextType(t *testing.T) {
+	ctx := gojwt.WithClaims(httptest.NewRequest(http.MethodGet, "/", nil).Context(), &ServiceClaims{})
+	if _, ok := gojwt.ClaimsFromContext[gojwt.Claims](ctx); ok {
+		t.Fatal("expected claims of another type to be absent")
+	}
+	if _, ok := gojwt.ClaimsFromContext[ServiceClaims](ctx); !ok {
+		t.Fatal("expected claims in context")
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Revoke(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	return false, errors.New("store is down")
+}
+
+func (failingStore) Revoked(ctx context.Context, id string) (bool, error) {
+	return false, errors.New("store is down")
+}
+
+func TestMiddlewareUnavailable(t *testing.T) {
+	keys := generateKeys(t)
+	idp := newKeySigner(keys[gojwt.ES256], "key-1")
+	server := newJWKSServer(t, idp)
+	server.Close()
+
+	store := gojwt.New(gojwt.Option{
+		Secret:          []byte("secret"),
+		ExpiresAfter:    time.Minute,
+		RevocationStore: failingStore{},
+	})
+	tests := []struct {
+		name   string
+		signer *gojwt.JwtSigner
+		token  string
+	}{
+		{"revocation store", store, signToken(t, store)},
+		{"key set", gojwt.New(gojwt.Option{KeySet: gojwt.NewRemoteKeySet(server.URL)}), signToken(t, idp)},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.signer.Verify(tt.token); !errors.Is(err, gojwt.ErrUnavailable) {
+				t.Fatalf("expected %v, got %v", gojwt.ErrUnavailable, err)
+			}
+
+			handler := tt.signer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Fatal("expected request to be rejected")
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+			if rr.Code != http.StatusServiceUnavailable {
+				t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+			}
+			if auth := rr.Header().Get("WWW-Authenticate"); auth != "" {
+				t.Fatalf("expected no WWW-Authenticate, got %q", auth)
+			}
+		})
+	}
+}
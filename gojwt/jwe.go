@@ -0,0 +1,374 @@
+package gojwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// Supported key management algorithms of the JWE.
+const (
+	Dir        = "dir"
+	RSAOAEP    = "RSA-OAEP"
+	RSAOAEP256 = "RSA-OAEP-256"
+	ECDHES     = "ECDH-ES"
+)
+
+// Supported content encryption algorithms of the JWE.
+const (
+	A128GCM = "A128GCM"
+	A256GCM = "A256GCM"
+)
+
+// Encryption encrypts the signed tokens as nested JWTs (RFC 7519 5.2), so
+// that the claims are confidential in transit and in logs.
+type Encryption struct {
+	// Algorithm is the key management algorithm, one of dir, RSA-OAEP,
+	// RSA-OAEP-256 or ECDH-ES. Only tokens with this algorithm are
+	// decrypted.
+	Algorithm string
+
+	// ContentEncryption is the content encryption algorithm, A128GCM or
+	// A256GCM. Defaults to A256GCM.
+	ContentEncryption string
+
+	// Key encrypts the tokens. It is the shared []byte key for dir, the
+	// recipient's *rsa.PublicKey for RSA-OAEP, or the recipient's
+	// *ecdsa.PublicKey on P-256 for ECDH-ES.
+	Key interface{}
+
+	// DecryptionKey decrypts the tokens, the *rsa.PrivateKey or
+	// *ecdsa.PrivateKey of the recipient. Defaults to the Key for dir.
+	DecryptionKey interface{}
+
+	// KeyID is set as the kid header of the JWE.
+	KeyID string
+}
+
+// JWEHeader is the JOSE header of the JWE.
+type JWEHeader struct {
+	Alg string `json:"alg"`
+	Enc string `json:"enc"`
+	Kid string `json:"kid,omitempty"`
+	Cty string `json:"cty,omitempty"`
+	Typ string `json:"typ,omitempty"`
+
+	// Epk is the ephemeral public key of ECDH-ES, and Apu and Apv are the
+	// optional agreement party info.
+	Epk *JWK   `json:"epk,omitempty"`
+	Apu string `json:"apu,omitempty"`
+	Apv string `json:"apv,omitempty"`
+
+	// Crit lists the header extensions that must be understood by the
+	// recipient. None are supported, so tokens with crit are rejected.
+	Crit []string `json:"crit,omitempty"`
+}
+
+// EncryptJWE encrypts the plaintext with the algorithms of the header, and
+// returns the compact serialization. See Encryption.Key for the key types.
+func EncryptJWE(header JWEHeader, key interface{}, plaintext []byte) (string, error) {
+	size, err := contentKeySize(header.Enc)
+	if err != nil {
+		return "", err
+	}
+	var cek, encryptedKey []byte
+	switch header.Alg {
+	case Dir:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) != size {
+			return "", fmt.Errorf("%w: %T for %s %s", ErrInvalidKey, key, header.Alg, header.Enc)
+		}
+		cek = secret
+	case RSAOAEP, RSAOAEP256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return "", fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, header.Alg)
+		}
+		cek = randomBytes(size)
+		encryptedKey, err = rsa.EncryptOAEP(oaepHash(header.Alg), rand.Reader, pub, cek, nil)
+		if err != nil {
+			return "", err
+		}
+	case ECDHES:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			return "", fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, header.Alg)
+		}
+		epk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		header.Epk = &JWK{
+			Kty: "EC",
+			Crv: "P-256",
+			X:   encodeBase64(padLeft(epk.X.Bytes(), 32)),
+			Y:   encodeBase64(padLeft(epk.Y.Bytes(), 32)),
+		}
+		cek, err = deriveECDHES(header, epk, pub, size)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf(`%w: "%s"`, ErrUnsupportedAlgorithm, header.Alg)
+	}
+
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	protected := encodeBase64(h)
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	iv := randomBytes(gcm.NonceSize())
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return strings.Join([]string{
+		protected,
+		encodeBase64(encryptedKey),
+		encodeBase64(iv),
+		encodeBase64(ciphertext),
+		encodeBase64(tag),
+	}, "."), nil
+}
+
+// DecryptJWE decrypts the compact serialization with the private key, or the
+// shared []byte key for dir. The caller must check that the alg header is
+// expected, see Encryption.Algorithm.
+func DecryptJWE(token string, key interface{}) (*JWEHeader, []byte, error) {
+	jwe, err := parseJWE(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := jwe.decrypt(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &jwe.header, plaintext, nil
+}
+
+// compactJWE is the decoded compact serialization of the JWE.
+type compactJWE struct {
+	header    JWEHeader
+	protected string
+	segments  [5][]byte
+}
+
+// parseJWE decodes the segments and the header of the JWE, without
+// decrypting it.
+func parseJWE(token string) (*compactJWE, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("%w: token contains an invalid number of segments", ErrMalformedToken)
+	}
+	jwe := &compactJWE{protected: parts[0]}
+	for i, part := range parts {
+		b, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMalformedToken, err)
+		}
+		jwe.segments[i] = b
+	}
+	if err := json.Unmarshal(jwe.segments[0], &jwe.header); err != nil {
+		return nil, fmt.Errorf("%w: header: %s", ErrMalformedToken, err)
+	}
+	if len(jwe.header.Crit) > 0 {
+		return nil, fmt.Errorf("%w: unsupported crit %v", ErrMalformedToken, jwe.header.Crit)
+	}
+	return jwe, nil
+}
+
+// decrypt decrypts the content with the key.
+func (jwe *compactJWE) decrypt(key interface{}) ([]byte, error) {
+	header := jwe.header
+	size, err := contentKeySize(header.Enc)
+	if err != nil {
+		return nil, err
+	}
+	encryptedKey := jwe.segments[1]
+
+	var cek []byte
+	switch header.Alg {
+	case Dir:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) != size {
+			return nil, fmt.Errorf("%w: %T for %s %s", ErrInvalidKey, key, header.Alg, header.Enc)
+		}
+		if len(encryptedKey) != 0 {
+			return nil, fmt.Errorf("%w: unexpected encrypted key", ErrMalformedToken)
+		}
+		cek = secret
+	case RSAOAEP, RSAOAEP256:
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, header.Alg)
+		}
+		cek, err = rsa.DecryptOAEP(oaepHash(header.Alg), rand.Reader, priv, encryptedKey, nil)
+		if err != nil || len(cek) != size {
+			// Continue with a random key, so that the padding errors
+			// cannot be told from the authentication errors (RFC 7516
+			// 11.5).
+			cek = randomBytes(size)
+		}
+	case ECDHES:
+		priv, ok := key.(*ecdsa.PrivateKey)
+		if !ok || priv.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, header.Alg)
+		}
+		if header.Epk == nil {
+			return nil, fmt.Errorf("%w: missing epk", ErrMalformedToken)
+		}
+		pub, err := header.Epk.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%w: epk: %s", ErrMalformedToken, err)
+		}
+		epk, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: epk is not an EC key", ErrMalformedToken)
+		}
+		if len(encryptedKey) != 0 {
+			return nil, fmt.Errorf("%w: unexpected encrypted key", ErrMalformedToken)
+		}
+		cek, err = deriveECDHES(header, priv, epk, size)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf(`%w: "%s"`, ErrUnsupportedAlgorithm, header.Alg)
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	iv, ciphertext, tag := jwe.segments[2], jwe.segments[3], jwe.segments[4]
+	if len(iv) != gcm.NonceSize() || len(tag) != gcm.Overhead() {
+		return nil, fmt.Errorf("%w: invalid iv or tag size", ErrMalformedToken)
+	}
+	plaintext, err := gcm.Open(nil, iv, append(ciphertext[:len(ciphertext):len(ciphertext)], tag...), []byte(jwe.protected))
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+// encrypt encrypts the signed token.
+func (e *Encryption) encrypt(token string) (string, error) {
+	header := JWEHeader{
+		Alg: e.Algorithm,
+		Enc: e.ContentEncryption,
+		Kid: e.KeyID,
+		Cty: "JWT",
+	}
+	return EncryptJWE(header, e.Key, []byte(token))
+}
+
+// decrypt decrypts the nested signed token. The algorithms are checked
+// before decrypting, so that the key is only used with the expected
+// algorithm.
+func (e *Encryption) decrypt(token string) (string, error) {
+	jwe, err := parseJWE(token)
+	if err != nil {
+		return "", err
+	}
+	header := jwe.header
+	if header.Alg != e.Algorithm || header.Enc != e.ContentEncryption {
+		return "", fmt.Errorf(`%w: "%s" "%s"`, ErrAlgorithmNotAllowed, header.Alg, header.Enc)
+	}
+	if !strings.EqualFold(header.Cty, "JWT") {
+		return "", fmt.Errorf("%w: expected nested JWT", ErrMalformedToken)
+	}
+	plaintext, err := jwe.decrypt(e.DecryptionKey)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// deriveECDHES derives the content encryption key with the Concat KDF (RFC
+// 7518 4.6.2) from the shared secret of the private and public key.
+func deriveECDHES(header JWEHeader, priv *ecdsa.PrivateKey, pub *ecdsa.PublicKey, size int) ([]byte, error) {
+	ecdhPriv, err := priv.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+	// ECDH rejects the points that are not on the curve.
+	ecdhPub, err := pub.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+	z, err := ecdhPriv.ECDH(ecdhPub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+
+	apu, err := base64.RawURLEncoding.DecodeString(header.Apu)
+	if err != nil {
+		return nil, fmt.Errorf("%w: apu: %s", ErrMalformedToken, err)
+	}
+	apv, err := base64.RawURLEncoding.DecodeString(header.Apv)
+	if err != nil {
+		return nil, fmt.Errorf("%w: apv: %s", ErrMalformedToken, err)
+	}
+
+	// The key of the direct key agreement is at most one round of SHA-256.
+	h := sha256.New()
+	h.Write([]byte{0, 0, 0, 1})
+	h.Write(z)
+	writeLengthPrefixed(h, []byte(header.Enc))
+	writeLengthPrefixed(h, apu)
+	writeLengthPrefixed(h, apv)
+	binary.Write(h, binary.BigEndian, uint32(size*8))
+	return h.Sum(nil)[:size], nil
+}
+
+func writeLengthPrefixed(h hash.Hash, b []byte) {
+	binary.Write(h, binary.BigEndian, uint32(len(b)))
+	h.Write(b)
+}
+
+func contentKeySize(enc string) (int, error) {
+	switch enc {
+	case A128GCM:
+		return 16, nil
+	case A256GCM:
+		return 32, nil
+	default:
+		return 0, fmt.Errorf(`%w: "%s"`, ErrUnsupportedAlgorithm, enc)
+	}
+}
+
+func oaepHash(alg string) hash.Hash {
+	if alg == RSAOAEP {
+		return sha1.New()
+	}
+	return sha256.New()
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,192 @@
+package gojwt_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/gojwt"
+)
+
+func TestEncryptedTokens(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	tests := []*gojwt.Encryption{
+		{Algorithm: gojwt.Dir, Key: secret},
+		{Algorithm: gojwt.Dir, ContentEncryption: gojwt.A128GCM, Key: secret[:16]},
+		{Algorithm: gojwt.RSAOAEP, Key: &rsaKey.PublicKey, DecryptionKey: rsaKey},
+		{Algorithm: gojwt.RSAOAEP256, Key: &rsaKey.PublicKey, DecryptionKey: rsaKey},
+		{Algorithm: gojwt.ECDHES, Key: &ecKey.PublicKey, DecryptionKey: ecKey, KeyID: "enc-1"},
+	}
+	for _, enc := range tests {
+		enc := enc
+		t.Run(enc.Algorithm+" "+enc.ContentEncryption, func(t *testing.T) {
+			signer := gojwt.New(gojwt.Option{
+				Secret:       []byte("secret"),
+				ExpiresAfter: 10 * time.Second,
+				Encryption:   enc,
+			})
+			token, err := signer.Sign(func(c *gojwt.Claims) error {
+				c.Email = "john.doe@mail.com"
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("signing failed: %v", err)
+			}
+			parts := strings.Split(token, ".")
+			if len(parts) != 5 {
+				t.Fatalf("expected JWE compact serialization, got %d segments", len(parts))
+			}
+			for _, part := range parts {
+				b, _ := base64.RawURLEncoding.DecodeString(part)
+				if strings.Contains(string(b), "john.doe") {
+					t.Fatalf("expected claims to be encrypted, got %s", b)
+				}
+			}
+
+			claims, err := signer.Verify(token)
+			if err != nil {
+				t.Fatalf("verify token failed: %v", err)
+			}
+			if claims.Email != "john.doe@mail.com" {
+				t.Fatalf("expected %s, got %s", "john.doe@mail.com", claims.Email)
+			}
+
+			// Tamper the ciphertext.
+			b := mustDecode(t, parts[3])
+			b[0] ^= 1
+			parts[3] = base64.RawURLEncoding.EncodeToString(b)
+			if _, err := signer.Verify(strings.Join(parts, ".")); !errors.Is(err, gojwt.ErrDecryptionFailed) {
+				t.Fatalf("expected %v, got %v", gojwt.ErrDecryptionFailed, err)
+			}
+		})
+	}
+}
+
+func TestEncryptedTokensRejectPlainJWS(t *testing.T) {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	plain := gojwt.New(gojwt.Option{
+		Secret:       []byte("secret"),
+		ExpiresAfter: 10 * time.Second,
+	})
+	encrypted := gojwt.New(gojwt.Option{
+		Secret:       []byte("secret"),
+		ExpiresAfter: 10 * time.Second,
+		Encryption:   &gojwt.Encryption{Algorithm: gojwt.Dir, Key: secret},
+	})
+	token, err := plain.Sign(func(c *gojwt.Claims) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if _, err := encrypted.Verify(token); !errors.Is(err, gojwt.ErrMalformedToken) {
+		t.Fatalf("expected %v, got %v", gojwt.ErrMalformedToken, err)
+	}
+}
+
+func TestEncryptedTokensAlgorithmPinning(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := gojwt.New(gojwt.Option{
+		Secret:       []byte("secret"),
+		ExpiresAfter: 10 * time.Second,
+		Encryption:   &gojwt.Encryption{Algorithm: gojwt.RSAOAEP, Key: &rsaKey.PublicKey},
+	})
+	recipient := gojwt.New(gojwt.Option{
+		Secret:       []byte("secret"),
+		ExpiresAfter: 10 * time.Second,
+		Encryption:   &gojwt.Encryption{Algorithm: gojwt.RSAOAEP256, DecryptionKey: rsaKey},
+	})
+	token, err := sender.Sign(func(c *gojwt.Claims) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if _, err := recipient.Verify(token); !errors.Is(err, gojwt.ErrAlgorithmNotAllowed) {
+		t.Fatalf("expected %v, got %v", gojwt.ErrAlgorithmNotAllowed, err)
+	}
+
+	// The algorithms are checked before the key is used to decrypt.
+	dir := gojwt.New(gojwt.Option{
+		Secret:       []byte("secret"),
+		ExpiresAfter: 10 * time.Second,
+		Encryption:   &gojwt.Encryption{Algorithm: gojwt.Dir, Key: make([]byte, 32)},
+	})
+	token, err = dir.Sign(func(c *gojwt.Claims) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if _, err := recipient.Verify(token); !errors.Is(err, gojwt.ErrAlgorithmNotAllowed) {
+		t.Fatalf("expected %v, got %v", gojwt.ErrAlgorithmNotAllowed, err)
+	}
+}
+
+func TestDecryptJWEVectorECDHES(t *testing.T) {
+	// RFC 7518 Appendix C, the key agreement of Alice's ephemeral key with
+	// Bob's key derives the content encryption key below.
+	header := `{"alg":"ECDH-ES","enc":"A128GCM","apu":"QWxpY2U","apv":"Qm9i","epk":{"kty":"EC","crv":"P-256","x":"gI0GAILBdu7T53akrFmMyGcsF3n5dO7MmwNBHKW5SV0","y":"SLW_xSffzlPWrHEVI30DHM_4egVwt3NQqeUD7nMFpps"}}`
+	cek := mustDecode(t, "VqqN6vgjbSBcIijNcacQGg")
+	bob := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(mustDecode(t, "weNJy2HscCSM6AEDTDg04biOvhFhyyWvOHQfeF_PxMQ")),
+			Y:     new(big.Int).SetBytes(mustDecode(t, "e8lnCO-AlStT-NJVX-crhB7QRYhiix03illJOVAOyck")),
+		},
+		D: new(big.Int).SetBytes(mustDecode(t, "VEmDZpDXXK8p8N0Cndsxs924q6nS1RXFASRl6BfUqdw")),
+	}
+
+	// Encrypt with the expected key.
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawURLEncoding
+	protected := enc.EncodeToString([]byte(header))
+	iv := make([]byte, gcm.NonceSize())
+	sealed := gcm.Seal(nil, iv, []byte("Live long and prosper."), []byte(protected))
+	tag := len(sealed) - gcm.Overhead()
+	token := strings.Join([]string{
+		protected,
+		"",
+		enc.EncodeToString(iv),
+		enc.EncodeToString(sealed[:tag]),
+		enc.EncodeToString(sealed[tag:]),
+	}, ".")
+
+	h, plaintext, err := gojwt.DecryptJWE(token, bob)
+	if err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	}
+	if h.Apu != "QWxpY2U" || string(plaintext) != "Live long and prosper." {
+		t.Fatalf("unexpected header %+v or plaintext %q", h, plaintext)
+	}
+}
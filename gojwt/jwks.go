@@ -0,0 +1,123 @@
+package gojwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+)
+
+// JWK represents a public JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+
+	// RSA keys.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC and OKP keys.
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS represents a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK returns the JWK of a RSA, P-256 or Ed25519 public key.
+func NewJWK(kid string, pub crypto.PublicKey) (JWK, error) {
+	alg, err := algorithmOf(pub)
+	if err != nil {
+		return JWK{}, err
+	}
+	jwk := JWK{Kid: kid, Use: "sig", Alg: alg}
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64(k.N.Bytes())
+		jwk.E = encodeBase64(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = k.Curve.Params().Name
+		jwk.X = encodeBase64(padLeft(k.X.Bytes(), size))
+		jwk.Y = encodeBase64(padLeft(k.Y.Bytes(), size))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64(k)
+	}
+	return jwk, nil
+}
+
+// JWKS returns the public keys of the signer, starting with the signing key,
+// followed by Option.PublicKeys ordered by kid.
+func (j *TypedSigner[C]) JWKS() (*JWKS, error) {
+	var jwks JWKS
+	if j.opt.PrivateKey != nil {
+		jwk, err := NewJWK(j.opt.KeyID, j.opt.PrivateKey.Public())
+		if err != nil {
+			return nil, err
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	kids := make([]string, 0, len(j.opt.PublicKeys))
+	for kid := range j.opt.PublicKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	for _, kid := range kids {
+		jwk, err := NewJWK(kid, j.opt.PublicKeys[kid])
+		if err != nil {
+			return nil, fmt.Errorf("gojwt: invalid key %q: %w", kid, err)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	if jwks.Keys == nil {
+		jwks.Keys = []JWK{}
+	}
+	return &jwks, nil
+}
+
+// JWKSHandler returns a http.Handler that serves the public keys as a JWKS
+// document, usually mounted at /.well-known/jwks.json.
+func (j *TypedSigner[C]) JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		jwks, err := j.JWKS()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		json.NewEncoder(w).Encode(jwks)
+	})
+}
+
+func encodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func padLeft(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	out := make([]byte, size)
+	copy(out[size-len(b):], b)
+	return out
+}
@@ -0,0 +1,194 @@
+package gojwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Header is the JOSE header of the JWS.
+type Header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+
+	// Crit lists the header extensions that must be understood by the
+	// recipient. None are supported, so tokens with crit are rejected.
+	Crit []string `json:"crit,omitempty"`
+}
+
+// JWS is a decoded JWS in the compact serialization (RFC 7515).
+type JWS struct {
+	Header  Header
+	Payload []byte
+
+	signingInput string
+	signature    []byte
+}
+
+// SignJWS signs the payload with the algorithm of the header, and returns the
+// compact serialization. The key is a []byte for HS256, *rsa.PrivateKey for
+// RS256, *ecdsa.PrivateKey for ES256 and ed25519.PrivateKey for EdDSA.
+func SignJWS(header Header, key interface{}, payload []byte) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodeBase64(h) + "." + encodeBase64(payload)
+	sig, err := signBytes(header.Alg, key, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encodeBase64(sig), nil
+}
+
+// ParseJWS decodes the compact serialization. The signature is not verified
+// until Verify is called, so the header and payload must not be trusted
+// before.
+func ParseJWS(token string) (*JWS, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: token contains an invalid number of segments", ErrMalformedToken)
+	}
+	h, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %s", ErrMalformedToken, err)
+	}
+	var header Header
+	if err := json.Unmarshal(h, &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %s", ErrMalformedToken, err)
+	}
+	if header.Alg == "" {
+		return nil, fmt.Errorf("%w: missing alg", ErrMalformedToken)
+	}
+	if len(header.Crit) > 0 {
+		return nil, fmt.Errorf("%w: unsupported crit %v", ErrMalformedToken, header.Crit)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload: %s", ErrMalformedToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %s", ErrMalformedToken, err)
+	}
+	return &JWS{
+		Header:       header,
+		Payload:      payload,
+		signingInput: parts[0] + "." + parts[1],
+		signature:    sig,
+	}, nil
+}
+
+// Verify verifies the signature with the public key, or the secret for HS256.
+// The caller must check that the alg header is allowed before, see
+// Option.Algorithms.
+func (j *JWS) Verify(key interface{}) error {
+	return verifyBytes(j.Header.Alg, key, []byte(j.signingInput), j.signature)
+}
+
+func signBytes(alg string, key interface{}, input []byte) ([]byte, error) {
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, alg)
+		}
+		if len(secret) == 0 {
+			return nil, ErrMissingKey
+		}
+		return createHMAC(secret, input), nil
+	case RS256:
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, alg)
+		}
+		return rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, sha256Sum(input))
+	case ES256:
+		priv, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, alg)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, priv, sha256Sum(input))
+		if err != nil {
+			return nil, err
+		}
+		// The signature is the concatenation of the fixed size R and S.
+		size := (priv.Curve.Params().BitSize + 7) / 8
+		return append(padLeft(r.Bytes(), size), padLeft(s.Bytes(), size)...), nil
+	case EdDSA:
+		priv, ok := key.(ed25519.PrivateKey)
+		if !ok || len(priv) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, alg)
+		}
+		return ed25519.Sign(priv, input), nil
+	default:
+		return nil, fmt.Errorf(`%w: "%s"`, ErrUnsupportedAlgorithm, alg)
+	}
+}
+
+func verifyBytes(alg string, key interface{}, input, sig []byte) error {
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) == 0 {
+			return fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, alg)
+		}
+		if !hmac.Equal(createHMAC(secret, input), sig) {
+			return ErrInvalidSignature
+		}
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sha256Sum(input), sig); err != nil {
+			return ErrInvalidSignature
+		}
+	case ES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, alg)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, sha256Sum(input), r, s) {
+			return ErrInvalidSignature
+		}
+	case EdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok || len(pub) != ed25519.PublicKeySize {
+			return fmt.Errorf("%w: %T for %s", ErrInvalidKey, key, alg)
+		}
+		if !ed25519.Verify(pub, input, sig) {
+			return ErrInvalidSignature
+		}
+	default:
+		return fmt.Errorf(`%w: "%s"`, ErrUnsupportedAlgorithm, alg)
+	}
+	return nil
+}
+
+func createHMAC(secret, input []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(input)
+	return mac.Sum(nil)
+}
+
+func sha256Sum(b []byte) []byte {
+	h := sha256.Sum256(b)
+	return h[:]
+}
@@ -0,0 +1,167 @@
+package gojwt_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/gojwt"
+)
+
+func generateKeys(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]crypto.Signer{
+		gojwt.RS256: rsaKey,
+		gojwt.ES256: ecKey,
+		gojwt.EdDSA: edKey,
+	}
+}
+
+func TestAsymmetricAlgorithms(t *testing.T) {
+	for alg, key := range generateKeys(t) {
+		alg, key := alg, key
+		t.Run(alg, func(t *testing.T) {
+			signer := gojwt.New(gojwt.Option{
+				ExpiresAfter: 10 * time.Second,
+				PrivateKey:   key,
+				KeyID:        "key-1",
+			})
+			token, err := signer.Sign(func(c *gojwt.Claims) error {
+				c.Subject = "user 1"
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("signing failed: %v", err)
+			}
+
+			var header struct {
+				Alg string `json:"alg"`
+				Kid string `json:"kid"`
+			}
+			b, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(b, &header); err != nil {
+				t.Fatal(err)
+			}
+			if header.Alg != alg || header.Kid != "key-1" {
+				t.Fatalf("expected alg %s and kid key-1, got %+v", alg, header)
+			}
+
+			claims, err := signer.Verify(token)
+			if err != nil {
+				t.Fatalf("verify token failed: %v", err)
+			}
+			if claims.Subject != "user 1" {
+				t.Fatalf("expected %s, got %s", "user 1", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	keys := generateKeys(t)
+	oldSigner := gojwt.New(gojwt.Option{
+		ExpiresAfter: 10 * time.Second,
+		PrivateKey:   keys[gojwt.RS256],
+		KeyID:        "old",
+	})
+	newSigner := gojwt.New(gojwt.Option{
+		ExpiresAfter: 10 * time.Second,
+		PrivateKey:   keys[gojwt.ES256],
+		KeyID:        "new",
+		PublicKeys: map[string]crypto.PublicKey{
+			"old": keys[gojwt.RS256].Public(),
+		},
+	})
+	token, err := oldSigner.Sign(func(c *gojwt.Claims) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if _, err := newSigner.Verify(token); err != nil {
+		t.Fatalf("expected token signed with the old key to be valid, got %v", err)
+	}
+
+	token, err = newSigner.Sign(func(c *gojwt.Claims) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if _, err := oldSigner.Verify(token); err == nil {
+		t.Fatal("expected token signed with an unknown kid to be invalid")
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	keys := generateKeys(t)
+	signer := gojwt.New(gojwt.Option{
+		PrivateKey: keys[gojwt.RS256],
+		KeyID:      "rsa",
+		PublicKeys: map[string]crypto.PublicKey{
+			"ed": keys[gojwt.EdDSA].Public(),
+			"ec": keys[gojwt.ES256].Public(),
+		},
+	})
+	rr := httptest.NewRecorder()
+	signer.JWKSHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/jwk-set+json" {
+		t.Fatalf("expected jwk-set content type, got %s", ct)
+	}
+
+	var jwks gojwt.JWKS
+	if err := json.NewDecoder(rr.Body).Decode(&jwks); err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct{ kid, kty, alg, crv string }{
+		{"rsa", "RSA", gojwt.RS256, ""},
+		{"ec", "EC", gojwt.ES256, "P-256"},
+		{"ed", "OKP", gojwt.EdDSA, "Ed25519"},
+	}
+	if len(jwks.Keys) != len(expected) {
+		t.Fatalf("expected %d keys, got %d", len(expected), len(jwks.Keys))
+	}
+	for i, want := range expected {
+		got := jwks.Keys[i]
+		if got.Kid != want.kid || got.Kty != want.kty || got.Alg != want.alg || got.Crv != want.crv {
+			t.Fatalf("expected %+v, got %+v", want, got)
+		}
+	}
+	if jwks.Keys[0].E != "AQAB" {
+		t.Fatalf("expected RSA exponent AQAB, got %s", jwks.Keys[0].E)
+	}
+
+	rr = httptest.NewRecorder()
+	signer.JWKSHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/.well-known/jwks.json", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status %d, got %d", http.StatusMethodNotAllowed, rr.Code)
+	}
+}
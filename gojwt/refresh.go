@@ -0,0 +1,112 @@
+package gojwt
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RefreshClaims are the claims of the refresh tokens. All refresh tokens that
+// are rotated from the same login share the same family.
+type RefreshClaims struct {
+	RegisteredClaims
+	Family string `json:"fid"`
+}
+
+// Refresher issues and rotates refresh tokens. Each refresh token can only be
+// used once. Using a refresh token again means that it has been stolen, so
+// the whole family is revoked, and both the attacker and the legitimate
+// client have to login again.
+type Refresher struct {
+	signer   *TypedSigner[RefreshClaims]
+	verifier *TypedSigner[RefreshClaims]
+	store    RevocationStore
+}
+
+// NewRefresher returns a new Refresher. ExpiresAfter is the lifetime of each
+// refresh token. Use a different key than the access tokens, so that refresh
+// tokens are not accepted as access tokens.
+func NewRefresher(opt TypedOption[RefreshClaims], store RevocationStore) *Refresher {
+	opt.RequiredClaims = append([]string{"sub", "jti", "fid"}, opt.RequiredClaims...)
+
+	// The verifier checks the revocation of the token itself, to tell
+	// revoked families from reused tokens.
+	verifierOpt := opt
+	verifierOpt.RevocationStore = nil
+	opt.RevocationStore = store
+	return &Refresher{
+		signer:   NewTyped(opt),
+		verifier: NewTyped(verifierOpt),
+		store:    store,
+	}
+}
+
+// Issue returns the refresh token of a new family for the subject, e.g. on
+// login.
+func (r *Refresher) Issue(ctx context.Context, subject string) (string, error) {
+	return r.signer.Sign(func(c *RefreshClaims) error {
+		c.Subject = subject
+		c.Family = newID()
+		return nil
+	})
+}
+
+// Rotate exchanges the refresh token for a new one of the same family. The
+// claims of the exchanged token are returned, to issue the access token for
+// the subject.
+func (r *Refresher) Rotate(ctx context.Context, token string) (string, *RefreshClaims, error) {
+	claims, err := r.verifier.VerifyContext(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	revoked, err := r.store.Revoked(ctx, familyKey(claims.Family))
+	if err != nil {
+		return "", nil, err
+	}
+	if revoked {
+		return "", nil, fmt.Errorf("invalid refresh token: %w", ErrTokenRevoked)
+	}
+
+	// Marking the token as used must be atomic, so that concurrent
+	// rotations with the same token are detected as reuse.
+	first, err := r.store.Revoke(ctx, claims.ID, r.verifier.remaining(time.Unix(claims.ExpiresAt, 0)))
+	if err != nil {
+		return "", nil, err
+	}
+	if !first {
+		if _, err := r.store.Revoke(ctx, familyKey(claims.Family), r.familyTTL()); err != nil {
+			return "", nil, err
+		}
+		return "", nil, fmt.Errorf("invalid refresh token: %w", ErrTokenReused)
+	}
+
+	next, err := r.signer.Sign(func(c *RefreshClaims) error {
+		*c = *claims
+		// Sign assigns a new jti.
+		c.ID = ""
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return next, claims, nil
+}
+
+// Revoke revokes the family of the refresh token, e.g. on logout.
+func (r *Refresher) Revoke(ctx context.Context, token string) error {
+	claims, err := r.verifier.VerifyContext(ctx, token)
+	if err != nil {
+		return err
+	}
+	_, err = r.store.Revoke(ctx, familyKey(claims.Family), r.familyTTL())
+	return err
+}
+
+// familyTTL is the lifetime of the newest token of the family.
+func (r *Refresher) familyTTL() time.Duration {
+	return r.verifier.remaining(r.verifier.opt.NowFunc().Add(r.verifier.opt.ExpiresAfter))
+}
+
+func familyKey(family string) string {
+	return "family:" + family
+}
@@ -0,0 +1,152 @@
+package gojwt_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/alextanhongpin/pkg/gojwt"
+)
+
+func revocationStores(t *testing.T) map[string]gojwt.RevocationStore {
+	t.Helper()
+	memory, cancel := gojwt.NewMemoryRevocationStore()
+	t.Cleanup(cancel)
+
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	return map[string]gojwt.RevocationStore{
+		"memory": memory,
+		"redis":  gojwt.NewRedisRevocationStore(client),
+	}
+}
+
+func newRefresher(store gojwt.RevocationStore) *gojwt.Refresher {
+	return gojwt.NewRefresher(gojwt.TypedOption[gojwt.RefreshClaims]{
+		Secret:       []byte("refresh secret"),
+		ExpiresAfter: 24 * time.Hour,
+	}, store)
+}
+
+func TestRefresherRotation(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range revocationStores(t) {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			refresher := newRefresher(store)
+			token, err := refresher.Issue(ctx, "user 1")
+			if err != nil {
+				t.Fatalf("issue failed: %v", err)
+			}
+			next, claims, err := refresher.Rotate(ctx, token)
+			if err != nil {
+				t.Fatalf("rotate failed: %v", err)
+			}
+			if claims.Subject != "user 1" {
+				t.Fatalf("expected %s, got %s", "user 1", claims.Subject)
+			}
+			last, nextClaims, err := refresher.Rotate(ctx, next)
+			if err != nil {
+				t.Fatalf("rotate failed: %v", err)
+			}
+			if nextClaims.Family != claims.Family || nextClaims.ID == claims.ID {
+				t.Fatalf("expected same family with new jti, got %+v and %+v", claims, nextClaims)
+			}
+
+			// The stolen first token is replayed.
+			if _, _, err := refresher.Rotate(ctx, token); !errors.Is(err, gojwt.ErrTokenReused) {
+				t.Fatalf("expected %v, got %v", gojwt.ErrTokenReused, err)
+			}
+			// The whole family is revoked.
+			if _, _, err := refresher.Rotate(ctx, last); !errors.Is(err, gojwt.ErrTokenRevoked) {
+				t.Fatalf("expected %v, got %v", gojwt.ErrTokenRevoked, err)
+			}
+
+			// Other families are not affected.
+			other, err := refresher.Issue(ctx, "user 1")
+			if err != nil {
+				t.Fatalf("issue failed: %v", err)
+			}
+			if _, _, err := refresher.Rotate(ctx, other); err != nil {
+				t.Fatalf("rotate failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestRefresherRevoke(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range revocationStores(t) {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			refresher := newRefresher(store)
+			token, err := refresher.Issue(ctx, "user 1")
+			if err != nil {
+				t.Fatalf("issue failed: %v", err)
+			}
+			next, _, err := refresher.Rotate(ctx, token)
+			if err != nil {
+				t.Fatalf("rotate failed: %v", err)
+			}
+			if err := refresher.Revoke(ctx, next); err != nil {
+				t.Fatalf("revoke failed: %v", err)
+			}
+			if _, _, err := refresher.Rotate(ctx, next); !errors.Is(err, gojwt.ErrTokenRevoked) {
+				t.Fatalf("expected %v, got %v", gojwt.ErrTokenRevoked, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRevokedToken(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range revocationStores(t) {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			signer := gojwt.New(gojwt.Option{
+				Secret:          []byte("secret"),
+				ExpiresAfter:    time.Minute,
+				RevocationStore: store,
+			})
+			token, err := signer.Sign(func(c *gojwt.Claims) error {
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("signing failed: %v", err)
+			}
+			claims, err := signer.VerifyContext(ctx, token)
+			if err != nil {
+				t.Fatalf("verify token failed: %v", err)
+			}
+			if claims.Id == "" {
+				t.Fatal("expected jti to be set")
+			}
+			if err := signer.Revoke(ctx, token); err != nil {
+				t.Fatalf("revoke failed: %v", err)
+			}
+			if _, err := signer.VerifyContext(ctx, token); !errors.Is(err, gojwt.ErrTokenRevoked) {
+				t.Fatalf("expected %v, got %v", gojwt.ErrTokenRevoked, err)
+			}
+		})
+	}
+}
+
+func TestRevokeWithoutStore(t *testing.T) {
+	signer := gojwt.New(gojwt.Option{Secret: []byte("secret")})
+	err := signer.Revoke(context.Background(), "token")
+	if !errors.Is(err, gojwt.ErrMissingRevocationStore) {
+		t.Fatalf("expected %v, got %v", gojwt.ErrMissingRevocationStore, err)
+	}
+}
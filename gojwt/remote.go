@@ -0,0 +1,285 @@
+package gojwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxJWKSSize is the max size of the JWKS response, so that a misbehaving
+// server cannot exhaust the memory.
+const maxJWKSSize = 1 << 20
+
+// ErrInvalidJWK is returned when a JWK cannot be converted to a public key.
+var ErrInvalidJWK = errors.New("gojwt: invalid jwk")
+
+// KeySet looks up the verification key by the kid of the token. It should
+// return ErrUnknownKey if the kid does not exist.
+type KeySet interface {
+	PublicKey(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// RemoteKeySet is a KeySet that fetches the keys from a JWKS URL, e.g. the
+// jwks_uri of an identity provider. The keys are cached by kid, and refetched
+// when an unknown kid is requested, at most once per refresh interval.
+type RemoteKeySet struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+	maxAge          time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetching    chan struct{}
+	fetchErr    error
+}
+
+// RemoteOption configures the RemoteKeySet.
+type RemoteOption func(*RemoteKeySet)
+
+// WithHTTPClient sets the client used to fetch the JWKS. Defaults to a client
+// with a 10 second timeout.
+func WithHTTPClient(client *http.Client) RemoteOption {
+	return func(r *RemoteKeySet) {
+		r.client = client
+	}
+}
+
+// WithRefreshInterval sets the minimum interval between fetches, so that
+// tokens with random kids cannot flood the JWKS URL. Defaults to 1 minute.
+func WithRefreshInterval(interval time.Duration) RemoteOption {
+	return func(r *RemoteKeySet) {
+		r.refreshInterval = interval
+	}
+}
+
+// WithMaxAge sets the duration after which the cached keys are refetched, so
+// that revoked keys are eventually removed. Defaults to 1 hour.
+func WithMaxAge(maxAge time.Duration) RemoteOption {
+	return func(r *RemoteKeySet) {
+		r.maxAge = maxAge
+	}
+}
+
+// NewRemoteKeySet returns a new KeySet for the JWKS URL. The keys are fetched
+// lazily on the first lookup.
+func NewRemoteKeySet(url string, opts ...RemoteOption) *RemoteKeySet {
+	r := &RemoteKeySet{
+		url:             url,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		refreshInterval: time.Minute,
+		maxAge:          time.Hour,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// PublicKey returns the cached key for the kid, and refetches the keys if the
+// kid is unknown or the cache is older than the max age. A stale key is
+// returned immediately while the keys are refetched in the background, and is
+// still returned if the refetch fails. Lookups of unknown kids wait for the
+// fetch, which is shared by the concurrent lookups.
+func (r *RemoteKeySet) PublicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	r.mu.Lock()
+	now := time.Now()
+	key, ok := r.keys[kid]
+	if ok && now.Sub(r.fetchedAt) <= r.maxAge {
+		r.mu.Unlock()
+		return key, nil
+	}
+	throttled := !r.attemptedAt.IsZero() && now.Sub(r.attemptedAt) < r.refreshInterval
+	if ok {
+		if !throttled {
+			r.refreshLocked()
+		}
+		r.mu.Unlock()
+		return key, nil
+	}
+	if throttled && r.fetching == nil {
+		// The kid may be valid if the last fetch failed.
+		err := r.fetchErr
+		r.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf(`%w: "%s"`, ErrUnknownKey, kid)
+	}
+	done := r.refreshLocked()
+	r.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if key, ok := r.keys[kid]; ok {
+		return key, nil
+	}
+	if r.fetchErr != nil {
+		return nil, r.fetchErr
+	}
+	return nil, fmt.Errorf(`%w: "%s"`, ErrUnknownKey, kid)
+}
+
+// Refresh fetches the keys immediately, e.g. to fail fast on startup.
+func (r *RemoteKeySet) Refresh(ctx context.Context) error {
+	r.mu.Lock()
+	done := r.refreshLocked()
+	r.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fetchErr
+}
+
+// refreshLocked starts fetching the keys unless a fetch is in flight, and
+// returns the channel that is closed when the fetch completes. The fetch is
+// shared by the callers, so it is not bound to their context, but to the
+// timeout of the client. It must be called with r.mu held.
+func (r *RemoteKeySet) refreshLocked() <-chan struct{} {
+	if r.fetching != nil {
+		return r.fetching
+	}
+	done := make(chan struct{})
+	r.fetching = done
+	r.attemptedAt = time.Now()
+	attemptedAt := r.attemptedAt
+
+	go func() {
+		defer close(done)
+
+		keys, err := r.fetch(context.Background())
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if err == nil {
+			r.keys = keys
+			r.fetchedAt = attemptedAt
+		}
+		r.fetchErr = err
+		r.fetching = nil
+	}()
+	return done
+}
+
+func (r *RemoteKeySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/jwk-set+json, application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gojwt: fetch jwks failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gojwt: fetch jwks failed: %s", resp.Status)
+	}
+	var jwks JWKS
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&jwks); err != nil {
+		return nil, fmt.Errorf("gojwt: decode jwks failed: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Skip the key types that are not supported, the JWKS may be
+		// shared with other applications.
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = pub
+	}
+	return keys, nil
+}
+
+// PublicKey returns the RSA, EC or Ed25519 public key of the JWK.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("%w: rsa exponent too large", ErrInvalidJWK)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf(`%w: unsupported curve "%s"`, ErrInvalidJWK, k.Crv)
+		}
+		x, err := decodeBase64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("%w: point is not on curve", ErrInvalidJWK)
+		}
+		return pub, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf(`%w: unsupported curve "%s"`, ErrInvalidJWK, k.Crv)
+		}
+		x, err := decodeBase64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid ed25519 key size", ErrInvalidJWK)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf(`%w: unsupported key type "%s"`, ErrInvalidJWK, k.Kty)
+	}
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidJWK, err)
+	}
+	return b, nil
+}
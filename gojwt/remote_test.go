@@ -0,0 +1,243 @@
+package gojwt_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/gojwt"
+)
+
+// jwksServer is a stand-in for the identity provider that serves the keys of
+// the given signers.
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	signers []*gojwt.JwtSigner
+	hits    int32
+}
+
+func newJWKSServer(t *testing.T, signers ...*gojwt.JwtSigner) *jwksServer {
+	t.Helper()
+	s := &jwksServer{signers: signers}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.hits, 1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var jwks gojwt.JWKS
+		for _, signer := range s.signers {
+			keys, err := signer.JWKS()
+			if err != nil {
+				t.Error(err)
+			}
+			jwks.Keys = append(jwks.Keys, keys.Keys...)
+		}
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) publish(signer *gojwt.JwtSigner) {
+	s.mu.Lock()
+	s.signers = append(s.signers, signer)
+	s.mu.Unlock()
+}
+
+func (s *jwksServer) Hits() int {
+	return int(atomic.LoadInt32(&s.hits))
+}
+
+func newKeySigner(key crypto.Signer, kid string) *gojwt.JwtSigner {
+	return gojwt.New(gojwt.Option{
+		ExpiresAfter: 10 * time.Second,
+		PrivateKey:   key,
+		KeyID:        kid,
+	})
+}
+
+func signToken(t *testing.T, signer *gojwt.JwtSigner) string {
+	t.Helper()
+	token, err := signer.Sign(func(c *gojwt.Claims) error {
+		c.Subject = "user 1"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	return token
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	keys := generateKeys(t)
+	idp := newKeySigner(keys[gojwt.RS256], "key-1")
+	server := newJWKSServer(t, idp)
+
+	verifier := gojwt.New(gojwt.Option{
+		KeySet: gojwt.NewRemoteKeySet(server.URL, gojwt.WithRefreshInterval(0)),
+	})
+	for i := 0; i < 3; i++ {
+		claims, err := verifier.Verify(signToken(t, idp))
+		if err != nil {
+			t.Fatalf("verify token failed: %v", err)
+		}
+		if claims.Subject != "user 1" {
+			t.Fatalf("expected %s, got %s", "user 1", claims.Subject)
+		}
+	}
+	if hits := server.Hits(); hits != 1 {
+		t.Fatalf("expected the keys to be cached, got %d fetches", hits)
+	}
+
+	// The identity provider rotates to a new key.
+	rotated := newKeySigner(keys[gojwt.EdDSA], "key-2")
+	server.publish(rotated)
+	if _, err := verifier.Verify(signToken(t, rotated)); err != nil {
+		t.Fatalf("expected unknown kid to refresh the keys, got %v", err)
+	}
+	if hits := server.Hits(); hits != 2 {
+		t.Fatalf("expected 2 fetches, got %d", hits)
+	}
+}
+
+func TestRemoteKeySetRateLimit(t *testing.T) {
+	keys := generateKeys(t)
+	idp := newKeySigner(keys[gojwt.ES256], "key-1")
+	server := newJWKSServer(t, idp)
+
+	keySet := gojwt.NewRemoteKeySet(server.URL, gojwt.WithRefreshInterval(time.Hour))
+	ctx := context.Background()
+	if _, err := keySet.PublicKey(ctx, "key-1"); err != nil {
+		t.Fatalf("expected key, got %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		_, err := keySet.PublicKey(ctx, "unknown")
+		if !errors.Is(err, gojwt.ErrUnknownKey) {
+			t.Fatalf("expected %v, got %v", gojwt.ErrUnknownKey, err)
+		}
+	}
+	if hits := server.Hits(); hits != 1 {
+		t.Fatalf("expected unknown kids to be rate limited, got %d fetches", hits)
+	}
+
+	// The cached keys are still served when the refetch is not allowed.
+	if _, err := keySet.PublicKey(ctx, "key-1"); err != nil {
+		t.Fatalf("expected key, got %v", err)
+	}
+}
+
+func TestRemoteKeySetMaxAge(t *testing.T) {
+	keys := generateKeys(t)
+	idp := newKeySigner(keys[gojwt.ES256], "key-1")
+	server := newJWKSServer(t, idp)
+
+	keySet := gojwt.NewRemoteKeySet(server.URL, gojwt.WithRefreshInterval(0), gojwt.WithMaxAge(0))
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := keySet.PublicKey(ctx, "key-1"); err != nil {
+			t.Fatalf("expected key, got %v", err)
+		}
+	}
+	// The expired keys are refetched in the background.
+	if err := keySet.Refresh(ctx); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if hits := server.Hits(); hits < 2 {
+		t.Fatalf("expected expired keys to be refetched, got %d fetches", hits)
+	}
+
+	// Stale keys are served when the identity provider is down.
+	server.Close()
+	if _, err := keySet.PublicKey(ctx, "key-1"); err != nil {
+		t.Fatalf("expected stale key, got %v", err)
+	}
+}
+
+func TestRemoteKeySetSlowFetch(t *testing.T) {
+	keys := generateKeys(t)
+	idp := newKeySigner(keys[gojwt.ES256], "key-1")
+	jwks, err := idp.JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hits int32
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) > 1 {
+			<-block
+		}
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	defer server.Close()
+	defer close(block)
+
+	keySet := gojwt.NewRemoteKeySet(server.URL, gojwt.WithRefreshInterval(0), gojwt.WithMaxAge(0))
+	ctx := context.Background()
+	if _, err := keySet.PublicKey(ctx, "key-1"); err != nil {
+		t.Fatalf("expected key, got %v", err)
+	}
+
+	// The refetch hangs, but the stale key is still served.
+	for i := 0; i < 3; i++ {
+		if _, err := keySet.PublicKey(ctx, "key-1"); err != nil {
+			t.Fatalf("expected stale key, got %v", err)
+		}
+	}
+
+	// Unknown kids wait for the refetch in flight until the context is done.
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := keySet.PublicKey(ctx, "key-2"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Fatalf("expected a single refetch, got %d fetches", n)
+	}
+}
+
+func TestRemoteKeySetMaxSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"keys":[`))
+		w.Write(bytes.Repeat([]byte(`{"kty":"EC"},`), 1<<17))
+		w.Write([]byte(`{"kty":"EC"}]}`))
+	}))
+	defer server.Close()
+
+	keySet := gojwt.NewRemoteKeySet(server.URL)
+	if err := keySet.Refresh(context.Background()); err == nil {
+		t.Fatal("expected oversized jwks to be rejected")
+	}
+}
+
+func TestRemoteKeySetRejectsHMAC(t *testing.T) {
+	keys := generateKeys(t)
+	server := newJWKSServer(t, newKeySigner(keys[gojwt.RS256], "key-1"))
+	verifier := gojwt.New(gojwt.Option{
+		KeySet: gojwt.NewRemoteKeySet(server.URL),
+	})
+	attacker := gojwt.New(gojwt.Option{
+		Secret:       []byte{},
+		ExpiresAfter: 10 * time.Second,
+	})
+	if _, err := attacker.Sign(func(c *gojwt.Claims) error { return nil }); !errors.Is(err, gojwt.ErrMissingKey) {
+		t.Fatalf("expected %v, got %v", gojwt.ErrMissingKey, err)
+	}
+	forger := gojwt.New(gojwt.Option{
+		Secret:       []byte("guess"),
+		ExpiresAfter: 10 * time.Second,
+	})
+	if _, err := verifier.Verify(signToken(t, forger)); err == nil {
+		t.Fatal("expected HS256 token to be rejected without secret")
+	}
+}
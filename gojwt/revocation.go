@@ -0,0 +1,46 @@
+package gojwt
+
+import (
+	"context"
+	"time"
+
+	"github.com/alextanhongpin/pkg/ttlmap"
+)
+
+// RevocationStore keeps track of the revoked token ids (the jti claim) and
+// token families.
+type RevocationStore interface {
+	// Revoke revokes the id for the given ttl, which should be at least the
+	// remaining lifetime of the token. It returns false if the id has
+	// already been revoked.
+	Revoke(ctx context.Context, id string, ttl time.Duration) (bool, error)
+
+	// Revoked reports whether the id has been revoked.
+	Revoked(ctx context.Context, id string) (bool, error)
+}
+
+// MemoryRevocationStore is an in-memory RevocationStore, suitable for a
+// single instance.
+type MemoryRevocationStore struct {
+	m *ttlmap.TTLMap
+}
+
+// NewMemoryRevocationStore returns a new in-memory revocation store, and a
+// function to stop the cleanup of expired ids.
+func NewMemoryRevocationStore() (*MemoryRevocationStore, func()) {
+	m, cancel := ttlmap.New()
+	return &MemoryRevocationStore{m: m}, cancel
+}
+
+func (s *MemoryRevocationStore) Revoke(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	return s.m.SetNX(id, struct{}{}, ttl), nil
+}
+
+func (s *MemoryRevocationStore) Revoked(ctx context.Context, id string) (bool, error) {
+	_, ok := s.m.Get(id)
+	return ok, nil
+}
+
+func newID() string {
+	return encodeBase64(randomBytes(16))
+}
@@ -0,0 +1,31 @@
+package gojwt
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisRevocationStore is a RevocationStore shared by multiple instances.
+type RedisRevocationStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisRevocationStore returns a new redis revocation store.
+func NewRedisRevocationStore(client *redis.Client) *RedisRevocationStore {
+	return &RedisRevocationStore{
+		client: client,
+		prefix: "gojwt:revoked:",
+	}
+}
+
+func (s *RedisRevocationStore) Revoke(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+id, 1, ttl).Result()
+}
+
+func (s *RedisRevocationStore) Revoked(ctx context.Context, id string) (bool, error) {
+	n, err := s.client.Exists(ctx, s.prefix+id).Result()
+	return n > 0, err
+}
@@ -0,0 +1,104 @@
+package gojwt
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// registeredClaims are the claims validated by Verify.
+type registeredClaims struct {
+	Issuer    string   `json:"iss"`
+	Audience  Audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	IssuedAt  *float64 `json:"iat"`
+	ID        string   `json:"jti"`
+}
+
+// defaultAlgorithms pins the algorithms to the ones of the configured keys,
+// so that a token cannot choose a weaker algorithm, e.g. HS256 signed with the
+// public key, or none.
+func defaultAlgorithms(alg string, publicKeys map[string]crypto.PublicKey, keySet KeySet) []string {
+	algs := []string{alg}
+	for _, pub := range publicKeys {
+		if alg, err := algorithmOf(pub); err == nil {
+			algs = append(algs, alg)
+		}
+	}
+	if keySet != nil {
+		algs = append(algs, RS256, ES256, EdDSA)
+	}
+	return algs
+}
+
+func (j *TypedSigner[C]) allowAlgorithm(alg string) error {
+	if !contains(j.opt.Algorithms, alg) {
+		return fmt.Errorf(`%w: "%s"`, ErrAlgorithmNotAllowed, alg)
+	}
+	return nil
+}
+
+// validateClaims validates and returns the registered claims of the decoded
+// payload.
+func (j *TypedSigner[C]) validateClaims(payload []byte) (*registeredClaims, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedToken, err)
+	}
+	for _, name := range j.opt.RequiredClaims {
+		if v, ok := raw[name]; !ok || string(v) == "null" {
+			return nil, &ClaimError{Claim: name, Err: ErrMissingClaim}
+		}
+	}
+	var c registeredClaims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedToken, err)
+	}
+
+	// The current time must be before the expiration time (RFC 7519 4.1.4).
+	now := j.opt.NowFunc()
+	leeway := j.opt.Leeway
+	if c.ExpiresAt != nil && !now.Before(unixTime(*c.ExpiresAt).Add(leeway)) {
+		return nil, &ClaimError{Claim: "exp", Err: ErrTokenExpired}
+	}
+	if c.NotBefore != nil && now.Add(leeway).Before(unixTime(*c.NotBefore)) {
+		return nil, &ClaimError{Claim: "nbf", Err: ErrTokenNotYetValid}
+	}
+	if c.IssuedAt != nil && now.Add(leeway).Before(unixTime(*c.IssuedAt)) {
+		return nil, &ClaimError{Claim: "iat", Err: ErrTokenNotYetValid}
+	}
+	if len(j.opt.Issuers) > 0 && !contains(j.opt.Issuers, c.Issuer) {
+		return nil, &ClaimError{Claim: "iss", Err: ErrInvalidIssuer}
+	}
+	if len(j.opt.Audiences) > 0 && !containsAny(j.opt.Audiences, c.Audience) {
+		return nil, &ClaimError{Claim: "aud", Err: ErrInvalidAudience}
+	}
+	return &c, nil
+}
+
+// unixTime converts the numeric date, which may have fractional seconds.
+func unixTime(sec float64) time.Time {
+	s, frac := math.Modf(sec)
+	return time.Unix(int64(s), int64(frac*1e9))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values []string, candidates []string) bool {
+	for _, c := range candidates {
+		if contains(values, c) {
+			return true
+		}
+	}
+	return false
+}
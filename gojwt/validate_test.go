@@ -0,0 +1,200 @@
+package gojwt_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/gojwt"
+)
+
+// encodeToken signs the raw header and payload with HS256.
+func encodeToken(secret, header, payload string) string {
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(header)) + "." + enc.EncodeToString([]byte(payload))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyAlgorithmPinning(t *testing.T) {
+	keys := generateKeys(t)
+	rsaSigner := newKeySigner(keys[gojwt.RS256], "key-1")
+
+	tests := []struct {
+		name   string
+		signer *gojwt.JwtSigner
+		token  string
+		err    error
+	}{
+		{
+			name:   "none",
+			signer: gojwt.New(gojwt.Option{Secret: []byte("secret")}),
+			token:  "eyJhbGciOiJub25lIn0.e30.",
+			err:    gojwt.ErrAlgorithmNotAllowed,
+		},
+		{
+			name:   "unknown",
+			signer: gojwt.New(gojwt.Option{Secret: []byte("secret")}),
+			token:  encodeToken("secret", `{"alg":"HS1024"}`, `{}`),
+			err:    gojwt.ErrAlgorithmNotAllowed,
+		},
+		{
+			name:   "hmac on asymmetric signer",
+			signer: rsaSigner,
+			token:  encodeToken("public key", `{"alg":"HS256","kid":"key-1"}`, `{}`),
+			err:    gojwt.ErrAlgorithmNotAllowed,
+		},
+		{
+			name: "key type mismatch",
+			signer: gojwt.New(gojwt.Option{
+				PrivateKey: keys[gojwt.RS256],
+				KeyID:      "key-1",
+				Algorithms: []string{gojwt.RS256, gojwt.ES256},
+			}),
+			token: signToken(t, newKeySigner(keys[gojwt.ES256], "key-1")),
+			err:   gojwt.ErrUnsupportedAlgorithm,
+		},
+		{
+			name:   "invalid signature",
+			signer: gojwt.New(gojwt.Option{Secret: []byte("secret")}),
+			token:  encodeToken("guess", `{"alg":"HS256"}`, `{}`),
+			err:    gojwt.ErrInvalidSignature,
+		},
+		{
+			name:   "malformed",
+			signer: gojwt.New(gojwt.Option{Secret: []byte("secret")}),
+			token:  "not.a.token",
+			err:    gojwt.ErrMalformedToken,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.signer.Verify(tt.token)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	nowFunc := func() time.Time {
+		return now
+	}
+	tests := []struct {
+		name    string
+		opt     gojwt.Option
+		now     time.Time
+		payload string
+		claim   string
+		err     error
+	}{
+		{
+			name:    "valid",
+			opt:     gojwt.Option{Issuers: []string{"a", "b"}, Audiences: []string{"c"}, RequiredClaims: []string{"sub"}},
+			payload: `{"iss":"b","aud":"c","sub":"user 1","exp":1600000001}`,
+		},
+		{
+			name:    "expires now",
+			payload: `{"exp":1600000000}`,
+			claim:   "exp",
+			err:     gojwt.ErrTokenExpired,
+		},
+		{
+			name:    "expired less than a second ago",
+			now:     time.Unix(1600000000, 900*int64(time.Millisecond)),
+			payload: `{"exp":1600000000}`,
+			claim:   "exp",
+			err:     gojwt.ErrTokenExpired,
+		},
+		{
+			name:    "expires in less than a second",
+			now:     time.Unix(1599999999, 900*int64(time.Millisecond)),
+			payload: `{"exp":1600000000}`,
+		},
+		{
+			name:    "expired",
+			payload: `{"exp":1599999999}`,
+			claim:   "exp",
+			err:     gojwt.ErrTokenExpired,
+		},
+		{
+			name:    "expired within leeway",
+			opt:     gojwt.Option{Leeway: time.Minute},
+			payload: `{"exp":1599999999}`,
+		},
+		{
+			name:    "not before",
+			payload: `{"nbf":1600000001}`,
+			claim:   "nbf",
+			err:     gojwt.ErrTokenNotYetValid,
+		},
+		{
+			name:    "not before within leeway",
+			opt:     gojwt.Option{Leeway: time.Minute},
+			payload: `{"nbf":1600000001}`,
+		},
+		{
+			name:    "issued in the future",
+			payload: `{"iat":1600000060}`,
+			claim:   "iat",
+			err:     gojwt.ErrTokenNotYetValid,
+		},
+		{
+			name:    "invalid issuer",
+			opt:     gojwt.Option{Issuers: []string{"a"}},
+			payload: `{"iss":"b"}`,
+			claim:   "iss",
+			err:     gojwt.ErrInvalidIssuer,
+		},
+		{
+			name:    "missing issuer",
+			opt:     gojwt.Option{Issuers: []string{"a"}},
+			payload: `{}`,
+			claim:   "iss",
+			err:     gojwt.ErrInvalidIssuer,
+		},
+		{
+			name:    "invalid audience",
+			opt:     gojwt.Option{Audiences: []string{"a"}},
+			payload: `{"aud":"b"}`,
+			claim:   "aud",
+			err:     gojwt.ErrInvalidAudience,
+		},
+		{
+			name:    "missing claim",
+			opt:     gojwt.Option{RequiredClaims: []string{"sub"}},
+			payload: `{"sub":null}`,
+			claim:   "sub",
+			err:     gojwt.ErrMissingClaim,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opt.Secret = []byte("secret")
+			tt.opt.NowFunc = nowFunc
+			if !tt.now.IsZero() {
+				tt.opt.NowFunc = func() time.Time {
+					return tt.now
+				}
+			}
+			signer := gojwt.New(tt.opt)
+
+			_, err := signer.Verify(encodeToken("secret", `{"alg":"HS256"}`, tt.payload))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			var cerr *gojwt.ClaimError
+			if tt.err != nil && (!errors.As(err, &cerr) || cerr.Claim != tt.claim) {
+				t.Fatalf("expected claim %s, got %v", tt.claim, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package hmac256
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// digestAlgorithms are the RFC 9530 hash algorithms, in order of preference.
+var digestAlgorithms = []struct {
+	name string
+	hash func() hash.Hash
+}{
+	{"sha-512", sha512.New},
+	{"sha-256", sha256.New},
+}
+
+// SetContentDigest sets the RFC 9530 Content-Digest header of the request with
+// the SHA-256 digest of the body, e.g. "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:".
+func SetContentDigest(r *http.Request) error {
+	body, err := readBody(r, 0)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	r.Header.Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+	return nil
+}
+
+// VerifyContentDigest checks the RFC 9530 Content-Digest header of the
+// request against the body. The strongest supported algorithm in the header is
+// used, and unsupported algorithms are ignored. Bodies larger than
+// DefaultMaxBodySize are rejected with ErrBodyTooLarge.
+func VerifyContentDigest(r *http.Request) error {
+	return verifyContentDigest(r, DefaultMaxBodySize)
+}
+
+func verifyContentDigest(r *http.Request, maxBodySize int64) error {
+	header := r.Header.Get("Content-Digest")
+	if header == "" {
+		return &HeaderError{Name: "Content-Digest", Err: ErrMissingHeader}
+	}
+	members, err := parseDictionary(header)
+	if err != nil {
+		return &HeaderError{Name: "Content-Digest", Err: ErrMalformedHeader}
+	}
+
+	body, err := readBody(r, maxBodySize)
+	if err != nil {
+		return err
+	}
+	for _, alg := range digestAlgorithms {
+		value, ok := members.get(alg.name)
+		if !ok {
+			continue
+		}
+		expected, err := parseByteSequence(value)
+		if err != nil {
+			return &HeaderError{Name: "Content-Digest", Err: ErrMalformedHeader}
+		}
+		h := alg.hash()
+		h.Write(body)
+		if subtle.ConstantTimeCompare(h.Sum(nil), expected) != 1 {
+			return fmt.Errorf("%w: content digest mismatch", ErrInvalidSignature)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: content digest", ErrUnsupportedAlgorithm)
+}
+
+// parseByteSequence parses the structured field byte sequence, e.g. ":aGVsbG8=:",
+// ignoring any parameters.
+func parseByteSequence(value string) ([]byte, error) {
+	if i := strings.IndexByte(value, ';'); i >= 0 {
+		value = value[:i]
+	}
+	value = strings.TrimSpace(value)
+	if len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+		return nil, ErrMalformedHeader
+	}
+	return base64.StdEncoding.DecodeString(value[1 : len(value)-1])
+}
@@ -0,0 +1,94 @@
+package hmac256
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrMissingHeader is returned when a required header is not present.
+	ErrMissingHeader = errors.New("hmac256: missing header")
+
+	// ErrMalformedHeader is returned when a header cannot be parsed.
+	ErrMalformedHeader = errors.New("hmac256: malformed header")
+
+	// ErrInvalidScheme is returned when the Authorization header does not
+	// use the configured bearer.
+	ErrInvalidScheme = errors.New("hmac256: invalid authorization scheme")
+
+	// ErrUnsupportedAlgorithm is returned when the algorithm in the
+	// Authorization header is not supported.
+	ErrUnsupportedAlgorithm = errors.New("hmac256: unsupported algorithm")
+
+	// ErrUnknownKey should be returned by the Repository when the access key
+	// ID does not exist.
+	ErrUnknownKey = errors.New("hmac256: unknown access key")
+
+	// ErrExpired is returned when the request date is older than
+	// Option.ExpiresAfter.
+	ErrExpired = errors.New("hmac256: request expired")
+
+	// ErrFutureDate is returned when the request date is further in the
+	// future than Option.ClockSkew.
+	ErrFutureDate = errors.New("hmac256: request date is in the future")
+
+	// ErrReplayed is returned when the nonce has already been used.
+	ErrReplayed = errors.New("hmac256: nonce has already been used")
+
+	// ErrMissingComponent is returned when the RFC 9421 signature does not
+	// cover a required component.
+	ErrMissingComponent = errors.New("hmac256: missing covered component")
+
+	// ErrBodyTooLarge is returned when the request body is larger than
+	// Option.MaxBodySize.
+	ErrBodyTooLarge = errors.New("hmac256: request body too large")
+
+	// ErrInvalidSignature is returned when the signature does not match the
+	// request.
+	ErrInvalidSignature = errors.New("hmac256: invalid signature")
+)
+
+// HeaderError describes the header that is missing or malformed.
+type HeaderError struct {
+	Name string
+	Err  error
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err, e.Name)
+}
+
+func (e *HeaderError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode maps the verification error to the HTTP status code. Missing or
+// malformed headers are client errors, except for a missing Authorization
+// header, which means the request is unauthenticated. Errors not returned by
+// this package, e.g. from the Repository, are server errors.
+func StatusCode(err error) int {
+	var herr *HeaderError
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &herr) && herr.Name == "Authorization" && errors.Is(herr.Err, ErrMissingHeader):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrMissingHeader),
+		errors.Is(err, ErrMalformedHeader),
+		errors.Is(err, ErrMissingComponent):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInvalidScheme),
+		errors.Is(err, ErrUnsupportedAlgorithm),
+		errors.Is(err, ErrUnknownKey),
+		errors.Is(err, ErrExpired),
+		errors.Is(err, ErrFutureDate),
+		errors.Is(err, ErrReplayed),
+		errors.Is(err, ErrInvalidSignature):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,11 +1,8 @@
 package hmac256
 
 import (
-	"crypto/hmac"
+	"context"
 	"crypto/sha256"
-	"crypto/subtle"
-	"encoding/base64"
-	"errors"
 	"fmt"
 	"net/http"
 	"sort"
@@ -24,9 +21,41 @@ type (
 	Option struct {
 		Bearer       string
 		ExpiresAfter time.Duration
+
+		// SignedHeaders are the additional headers signed by SignRequest,
+		// e.g. Content-Type. The host and the X-<Bearer>-* headers are
+		// always signed.
+		SignedHeaders []string
+
+		// ClockSkew is the tolerance for dates in the future, since the
+		// clocks of the client and the server may drift.
+		ClockSkew time.Duration
+
+		// NonceStore keeps track of the used nonces to reject replayed
+		// requests. The caller owns the given store. Defaults to an
+		// in-memory store shared by the signers, which is created on first
+		// use and lives as long as the process.
+		NonceStore NonceStore
+
+		// RequireNonce rejects the requests without the X-<Bearer>-Nonce
+		// header, or the RFC 9421 signatures without the nonce parameter.
+		// The nonces that are sent are always checked for replays. Without
+		// a nonce, a signature can be replayed until it expires. Enable it
+		// once all clients send the nonce.
+		RequireNonce bool
+
+		// MaxBodySize is the max size in bytes of the request bodies that
+		// are read to verify the signature. Defaults to
+		// DefaultMaxBodySize. A negative size disables the limit.
+		MaxBodySize int64
+
+		// RequiredComponents are the components that the RFC 9421
+		// signatures must cover. Defaults to DefaultRequiredComponents.
+		RequiredComponents []string
 	}
 
-	// Repository represents a lookup interface for the secret key.
+	// Repository represents a lookup interface for the secret key. It should
+	// return ErrUnknownKey if the access key ID does not exist.
 	Repository interface {
 		LookupSecretKey(accessKeyID string) (string, error)
 	}
@@ -36,26 +65,54 @@ type (
 		ConvertMapToHeaders(fields map[string]interface{}) http.Header
 		SignHeaders(secretKey string, header http.Header) string
 		ValidateHeader(header http.Header) error
+		ValidateHeaderContext(ctx context.Context, header http.Header) error
 		NewAuthorizationHeader(accessKeyID, signature string) string
+		SignRequest(r *http.Request, accessKeyID, secretKey string) error
+		VerifyRequest(r *http.Request) error
 	}
 
 	// SignerImpl implements the Signer interface.
 	SignerImpl struct {
-		opt          Option
-		repo         Repository
-		headerDate   string
-		headerPrefix string
+		opt                 Option
+		keys                KeyRepository
+		headerDate          string
+		headerNonce         string
+		headerPrefix        string
+		headerContentSHA256 string
 	}
 )
 
+// DefaultMaxBodySize is the default Option.MaxBodySize.
+const DefaultMaxBodySize = 10 << 20
+
 // NewSigner returns a new hmac 256 signer.
 func NewSigner(opt Option, repo Repository) *SignerImpl {
+	return NewSignerWithKeys(opt, secretKeyRepository{repo})
+}
+
+// NewSignerWithKeys returns a new signer that verifies the signatures against
+// all active keys of the access key ID, and supports other algorithms than
+// HMAC-SHA256.
+func NewSignerWithKeys(opt Option, keys KeyRepository) *SignerImpl {
 	opt.Bearer = strings.Title(opt.Bearer)
 	return &SignerImpl{
-		opt:          opt,
-		repo:         repo,
-		headerDate:   newHeaderDate(opt.Bearer),
-		headerPrefix: newHeaderPrefix(opt.Bearer),
+		opt:                 opt,
+		keys:                keys,
+		headerDate:          newHeaderDate(opt.Bearer),
+		headerNonce:         newHeaderNonce(opt.Bearer),
+		headerPrefix:        newHeaderPrefix(opt.Bearer),
+		headerContentSHA256: newHeaderContentSHA256(opt.Bearer),
+	}
+}
+
+func (s *SignerImpl) maxBodySize() int64 {
+	switch {
+	case s.opt.MaxBodySize == 0:
+		return DefaultMaxBodySize
+	case s.opt.MaxBodySize < 0:
+		return 0
+	default:
+		return s.opt.MaxBodySize
 	}
 }
 
@@ -74,16 +131,19 @@ func (s *SignerImpl) ConvertMapToHeaders(fields map[string]interface{}) http.Hea
 }
 
 // SignHeaders takes a http.Header, orders them alphabetically, and concatenate
-// the header name and values before signing them with a secret key.
+// the header name and values before signing them with a secret key. The date
+// and nonce headers are set if not present.
 func (s *SignerImpl) SignHeaders(secretKey string, header http.Header) string {
-	if v := header.Get(s.headerDate); v == "" {
-		header.Set(s.headerDate, strconv.FormatInt(time.Now().Unix(), 10))
-	}
-	var headers []Header
-	for key, values := range header {
-		headers = append(headers, Header{key, values[0]})
-	}
-	return createSignature(secretKey, concatHeaders(headers...))
+	s.setDefaultHeaders(header)
+	return createHMAC(sha256.New, []byte(secretKey), concatHeaderValues(header))
+}
+
+// SignHeadersWithKey is like SignHeaders, but signs with the algorithm of the
+// key. Use NewAuthorizationHeaderWithAlgorithm to create the matching
+// Authorization header.
+func (s *SignerImpl) SignHeadersWithKey(key Key, header http.Header) (string, error) {
+	s.setDefaultHeaders(header)
+	return key.sign(concatHeaderValues(header))
 }
 
 // NewAuthorizationHeader takes in the accessKeyID and signature and returns a
@@ -92,51 +152,131 @@ func (s *SignerImpl) NewAuthorizationHeader(accessKeyID, signature string) strin
 	return fmt.Sprintf("%s %s:%s", s.opt.Bearer, accessKeyID, signature)
 }
 
+// NewAuthorizationHeaderWithAlgorithm returns a new Authorization header with
+// the algorithm identifier, e.g. "Bearer HMAC-SHA512 accessKeyID:signature".
+// HMAC-SHA256 omits the identifier to remain compatible with older verifiers.
+func (s *SignerImpl) NewAuthorizationHeaderWithAlgorithm(algorithm Algorithm, accessKeyID, signature string) string {
+	if algorithm == HMACSHA256 {
+		return s.NewAuthorizationHeader(accessKeyID, signature)
+	}
+	return fmt.Sprintf("%s %s %s:%s", s.opt.Bearer, algorithm, accessKeyID, signature)
+}
+
 // ValidateHeader takes a http.Header, checks if the Authorization header is
 // valid and attempts to reconstruct the signature to check the validity of the
 // payload.
 func (s *SignerImpl) ValidateHeader(header http.Header) error {
-	authorization := header.Get("Authorization")
-	parts := strings.Split(authorization, " ")
-	if len(parts) != 2 {
-		// Error message should be conveying a message - x is invalid,
-		// x is required
-		return errors.New("authorization header is invalid")
-	}
-	bearer, token := parts[0], parts[1]
-	if bearer != s.opt.Bearer {
-		return fmt.Errorf(`bearer "%s" is invalid`, bearer)
-	}
-	tokenParts := strings.Split(token, ":")
-	if len(tokenParts) != 2 {
-		return errors.New("token is invalid")
+	return s.ValidateHeaderContext(context.Background(), header)
+}
+
+// ValidateHeaderContext is like ValidateHeader, but passes the context to the
+// key lookup.
+func (s *SignerImpl) ValidateHeaderContext(ctx context.Context, header http.Header) error {
+	algorithm, accessKey, signature, err := s.parseAuthorization(header)
+	if err != nil {
+		return err
 	}
-	accessKey, signature := tokenParts[0], tokenParts[1]
-	secretKey, err := s.repo.LookupSecretKey(accessKey)
+	keys, err := s.keys.LookupKeys(ctx, accessKey)
 	if err != nil {
 		return err
 	}
-	if err := validateHeaderDate(header, s.headerDate, s.opt.ExpiresAfter); err != nil {
+	if err := s.validateFreshness(header); err != nil {
 		return err
 	}
 	headersWithPrefix := selectHeadersWithPrefix(s.headerPrefix, header)
-	encodedSignature := s.SignHeaders(secretKey, headersWithPrefix)
-	if subtle.ConstantTimeCompare([]byte(encodedSignature), []byte(signature)) != 1 {
-		return errors.New("invalid signature")
+	if err := verifyAny(keys, algorithm, concatHeaderValues(headersWithPrefix), signature); err != nil {
+		return err
+	}
+	return s.useNonce(header)
+}
+
+func (s *SignerImpl) setDefaultHeaders(header http.Header) {
+	if v := header.Get(s.headerDate); v == "" {
+		header.Set(s.headerDate, strconv.FormatInt(time.Now().Unix(), 10))
+	}
+	if v := header.Get(s.headerNonce); v == "" {
+		header.Set(s.headerNonce, newNonce())
+	}
+}
+
+// validateFreshness checks the date and the presence of the nonce before the
+// signature is verified.
+func (s *SignerImpl) validateFreshness(header http.Header) error {
+	if err := validateHeaderDate(header, s.headerDate, s.opt.ExpiresAfter, s.opt.ClockSkew); err != nil {
+		return err
+	}
+	if s.opt.RequireNonce && header.Get(s.headerNonce) == "" {
+		return &HeaderError{Name: s.headerNonce, Err: ErrMissingHeader}
+	}
+	return nil
+}
+
+// useNonce marks the nonce as used. The nonce only needs to be remembered for
+// as long as the date is valid.
+func (s *SignerImpl) useNonce(header http.Header) error {
+	nonce := header.Get(s.headerNonce)
+	if nonce == "" {
+		return nil
+	}
+	ok, err := s.nonceStore().Add(nonce, s.opt.ExpiresAfter+s.opt.ClockSkew)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrReplayed
 	}
 	return nil
 }
 
-func validateHeaderDate(header http.Header, headerDate string, expiresAfter time.Duration) error {
+// parseAuthorization parses the Authorization header in the format
+// "<Bearer> [<Algorithm>] <AccessKeyID>:<Signature>". The algorithm defaults to
+// HMAC-SHA256.
+func (s *SignerImpl) parseAuthorization(header http.Header) (algorithm Algorithm, accessKey, signature string, err error) {
+	authorization := header.Get("Authorization")
+	if authorization == "" {
+		return "", "", "", &HeaderError{Name: "Authorization", Err: ErrMissingHeader}
+	}
+	parts := strings.Split(authorization, " ")
+	var bearer, token string
+	switch len(parts) {
+	case 2:
+		bearer, algorithm, token = parts[0], HMACSHA256, parts[1]
+	case 3:
+		bearer, algorithm, token = parts[0], Algorithm(parts[1]), parts[2]
+	default:
+		return "", "", "", &HeaderError{Name: "Authorization", Err: ErrMalformedHeader}
+	}
+	if bearer != s.opt.Bearer {
+		return "", "", "", fmt.Errorf(`%w: "%s"`, ErrInvalidScheme, bearer)
+	}
+	switch algorithm {
+	case HMACSHA256, HMACSHA512, Ed25519:
+	default:
+		return "", "", "", fmt.Errorf(`%w: "%s"`, ErrUnsupportedAlgorithm, algorithm)
+	}
+	tokenParts := strings.Split(token, ":")
+	if len(tokenParts) != 2 {
+		return "", "", "", &HeaderError{Name: "Authorization", Err: ErrMalformedHeader}
+	}
+	return algorithm, tokenParts[0], tokenParts[1], nil
+}
+
+func validateHeaderDate(header http.Header, headerDate string, expiresAfter, clockSkew time.Duration) error {
 	var date time.Time
 	dateStr := header.Get(headerDate)
+	if dateStr == "" {
+		return &HeaderError{Name: headerDate, Err: ErrMissingHeader}
+	}
 	dateInt, err := strconv.ParseInt(dateStr, 10, 64)
 	if err != nil {
-		return err
+		return &HeaderError{Name: headerDate, Err: ErrMalformedHeader}
 	}
 	date = time.Unix(dateInt, 0)
 	if time.Since(date) > expiresAfter {
-		return errors.New("token expired")
+		return ErrExpired
+	}
+	if time.Until(date) > clockSkew {
+		return ErrFutureDate
 	}
 	return nil
 }
@@ -146,7 +286,7 @@ func selectHeadersWithPrefix(prefix string, header http.Header) http.Header {
 	l := len(prefix)
 	for key, values := range header {
 		if strings.EqualFold(key[:min(l, len(key))], prefix) {
-			result.Set(key, values[0])
+			result[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
 		}
 	}
 	return result
@@ -160,6 +300,22 @@ func newHeaderDate(bearer string) string {
 	return fmt.Sprintf("X-%s-Date", bearer)
 }
 
+func newHeaderNonce(bearer string) string {
+	return fmt.Sprintf("X-%s-Nonce", bearer)
+}
+
+func newHeaderContentSHA256(bearer string) string {
+	return fmt.Sprintf("X-%s-Content-Sha256", bearer)
+}
+
+func concatHeaderValues(header http.Header) string {
+	var headers []Header
+	for key, values := range header {
+		headers = append(headers, Header{key, strings.Join(values, ",")})
+	}
+	return concatHeaders(headers...)
+}
+
 func concatHeaders(headers ...Header) string {
 	sort.Slice(headers, func(i, j int) bool {
 		return headers[i].name < headers[j].name
@@ -171,12 +327,6 @@ func concatHeaders(headers ...Header) string {
 	return strings.Join(result, " ")
 }
 
-func createSignature(secret, data string) string {
-	h := hmac.New(sha256.New, []byte(secret))
-	h.Write([]byte(data))
-	return base64.URLEncoding.EncodeToString(h.Sum(nil))
-}
-
 func min(hd int, rest ...int) int {
 	for _, n := range rest {
 		if n < hd {
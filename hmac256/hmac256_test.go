@@ -0,0 +1,239 @@
+package hmac256_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/hmac256"
+)
+
+type repository map[string]string
+
+func (r repository) LookupSecretKey(accessKeyID string) (string, error) {
+	secretKey, ok := r[accessKeyID]
+	if !ok {
+		return "", hmac256.ErrUnknownKey
+	}
+	return secretKey, nil
+}
+
+func newSigner() *hmac256.SignerImpl {
+	return hmac256.NewSigner(hmac256.Option{
+		Bearer:        "Acme",
+		ExpiresAfter:  time.Minute,
+		SignedHeaders: []string{"Content-Type"},
+	}, repository{"access-key": "secret-key"})
+}
+
+func TestSignRequest(t *testing.T) {
+	signer := newSigner()
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest("POST", "http://example.com/books?b=2&a=1&a=0", strings.NewReader(`{"title":"go"}`))
+		r.Header.Set("Content-Type", "application/json")
+		if err := signer.SignRequest(r, "access-key", "secret-key"); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	r := newRequest()
+	if err := signer.VerifyRequest(r); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	if string(body) != `{"title":"go"}` {
+		t.Fatalf("expected body to be readable after verify, got %q", body)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(*http.Request)
+	}{
+		{"method", func(r *http.Request) { r.Method = "PUT" }},
+		{"path", func(r *http.Request) { r.URL.Path = "/authors" }},
+		{"query", func(r *http.Request) { r.URL.RawQuery = "a=1&b=2" }},
+		{"host", func(r *http.Request) { r.Host = "evil.com" }},
+		{"signed header", func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") }},
+		{"body", func(r *http.Request) { r.Body = ioutil.NopCloser(strings.NewReader(`{"title":"rust"}`)) }},
+		{"unknown key", func(r *http.Request) {
+			r.Header.Set("Authorization", strings.Replace(r.Header.Get("Authorization"), "access-key", "unknown", 1))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRequest()
+			tt.tamper(r)
+			if err := signer.VerifyRequest(r); err == nil {
+				t.Fatalf("expected tampered %s to be rejected", tt.name)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	signer := newSigner()
+
+	header := signer.ConvertMapToHeaders(map[string]interface{}{"user": "john"})
+	signature := signer.SignHeaders("secret-key", header)
+	header.Set("Authorization", signer.NewAuthorizationHeader("access-key", signature))
+
+	if err := signer.ValidateHeader(header); err != nil {
+		t.Fatalf("expected valid header, got %v", err)
+	}
+	if err := signer.ValidateHeader(header); err == nil {
+		t.Fatalf("expected replayed header to be rejected")
+	}
+
+	r := httptest.NewRequest("GET", "http://example.com/books", nil)
+	if err := signer.SignRequest(r, "access-key", "secret-key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.VerifyRequest(r); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	if err := signer.VerifyRequest(r); err == nil {
+		t.Fatalf("expected replayed request to be rejected")
+	}
+}
+
+func TestFutureDate(t *testing.T) {
+	signer := hmac256.NewSigner(hmac256.Option{
+		Bearer:       "Acme",
+		ExpiresAfter: time.Minute,
+		ClockSkew:    5 * time.Second,
+	}, repository{"access-key": "secret-key"})
+
+	tests := []struct {
+		name  string
+		date  time.Time
+		valid bool
+	}{
+		{"within skew", time.Now().Add(3 * time.Second), true},
+		{"beyond skew", time.Now().Add(time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := signer.ConvertMapToHeaders(map[string]interface{}{
+				"Date": tt.date.Unix(),
+			})
+			signature := signer.SignHeaders("secret-key", header)
+			header.Set("Authorization", signer.NewAuthorizationHeader("access-key", signature))
+
+			err := signer.ValidateHeader(header)
+			if valid := err == nil; valid != tt.valid {
+				t.Fatalf("expected valid %t, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestValidateHeaderErrors(t *testing.T) {
+	signer := hmac256.NewSigner(hmac256.Option{
+		Bearer:       "Acme",
+		ExpiresAfter: time.Minute,
+		RequireNonce: true,
+	}, repository{"access-key": "secret-key"})
+
+	newHeader := func() http.Header {
+		header := signer.ConvertMapToHeaders(map[string]interface{}{"user": "john"})
+		signature := signer.SignHeaders("secret-key", header)
+		header.Set("Authorization", signer.NewAuthorizationHeader("access-key", signature))
+		return header
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(http.Header)
+		err    error
+		code   int
+	}{
+		{"missing authorization", func(h http.Header) { h.Del("Authorization") }, hmac256.ErrMissingHeader, http.StatusUnauthorized},
+		{"malformed authorization", func(h http.Header) { h.Set("Authorization", "Acme") }, hmac256.ErrMalformedHeader, http.StatusBadRequest},
+		{"invalid scheme", func(h http.Header) { h.Set("Authorization", "Basic access-key:sig") }, hmac256.ErrInvalidScheme, http.StatusUnauthorized},
+		{"unknown key", func(h http.Header) { h.Set("Authorization", "Acme unknown:sig") }, hmac256.ErrUnknownKey, http.StatusUnauthorized},
+		{"missing date", func(h http.Header) { h.Del("X-Acme-Date") }, hmac256.ErrMissingHeader, http.StatusBadRequest},
+		{"malformed date", func(h http.Header) { h.Set("X-Acme-Date", "yesterday") }, hmac256.ErrMalformedHeader, http.StatusBadRequest},
+		{"expired", func(h http.Header) { h.Set("X-Acme-Date", "0") }, hmac256.ErrExpired, http.StatusUnauthorized},
+		{"missing nonce", func(h http.Header) { h.Del("X-Acme-Nonce") }, hmac256.ErrMissingHeader, http.StatusBadRequest},
+		{"invalid signature", func(h http.Header) { h.Set("X-Acme-User", "jane") }, hmac256.ErrInvalidSignature, http.StatusUnauthorized},
+		{"unsigned value", func(h http.Header) { h.Add("X-Acme-User", "jane") }, hmac256.ErrInvalidSignature, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := newHeader()
+			tt.tamper(header)
+
+			err := signer.ValidateHeader(header)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if code := hmac256.StatusCode(err); code != tt.code {
+				t.Fatalf("expected status %d, got %d", tt.code, code)
+			}
+		})
+	}
+}
+
+func TestMultiValueHeader(t *testing.T) {
+	signer := newSigner()
+
+	header := signer.ConvertMapToHeaders(map[string]interface{}{"role": "reader"})
+	header.Add("X-Acme-Role", "writer")
+	signature := signer.SignHeaders("secret-key", header)
+	header.Set("Authorization", signer.NewAuthorizationHeader("access-key", signature))
+
+	tampered := header.Clone()
+	tampered["X-Acme-Role"] = []string{"reader", "admin"}
+	if err := signer.ValidateHeader(tampered); !errors.Is(err, hmac256.ErrInvalidSignature) {
+		t.Fatalf("expected tampered second value to be rejected, got %v", err)
+	}
+	if err := signer.ValidateHeader(header); err != nil {
+		t.Fatalf("expected valid header, got %v", err)
+	}
+}
+
+func TestNewSignerGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		newSigner()
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("expected %d goroutines, got %d", before, after)
+	}
+}
+
+func TestRequireNonce(t *testing.T) {
+	// Clients before the nonce was introduced only sign the date.
+	date := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte("secret-key"))
+	mac.Write([]byte("X-Acme-Date:" + date + " X-Acme-User:john"))
+	header := http.Header{}
+	header.Set("X-Acme-Date", date)
+	header.Set("X-Acme-User", "john")
+	header.Set("Authorization", "Acme access-key:"+base64.URLEncoding.EncodeToString(mac.Sum(nil)))
+
+	if err := newSigner().ValidateHeader(header); err != nil {
+		t.Fatalf("expected header without nonce to be valid, got %v", err)
+	}
+
+	signer := hmac256.NewSigner(hmac256.Option{
+		Bearer:       "Acme",
+		ExpiresAfter: time.Minute,
+		RequireNonce: true,
+	}, repository{"access-key": "secret-key"})
+	var herr *hmac256.HeaderError
+	if err := signer.ValidateHeader(header); !errors.As(err, &herr) || herr.Name != "X-Acme-Nonce" || !errors.Is(err, hmac256.ErrMissingHeader) {
+		t.Fatalf("expected missing nonce, got %v", err)
+	}
+}
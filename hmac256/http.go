@@ -0,0 +1,71 @@
+package hmac256
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey string
+
+const accessKeyIDContextKey = contextKey("hmac256_access_key_id")
+
+// WithAccessKeyID populates the context with the authenticated access key ID.
+func WithAccessKeyID(ctx context.Context, accessKeyID string) context.Context {
+	return context.WithValue(ctx, accessKeyIDContextKey, accessKeyID)
+}
+
+// AccessKeyID extracts the authenticated access key ID from the context.
+func AccessKeyID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(accessKeyIDContextKey).(string)
+	return id, ok
+}
+
+type transport struct {
+	signer      *SignerImpl
+	accessKeyID string
+	key         Key
+	next        http.RoundTripper
+}
+
+// Transport returns a http.RoundTripper that signs every outgoing request with
+// SignRequest. The original request is not modified. Defaults to
+// http.DefaultTransport if next is nil.
+func (s *SignerImpl) Transport(accessKeyID, secretKey string, next http.RoundTripper) http.RoundTripper {
+	return s.TransportWithKey(accessKeyID, Key{Algorithm: HMACSHA256, Secret: []byte(secretKey)}, next)
+}
+
+// TransportWithKey is like Transport, but signs with the algorithm of the key.
+func (s *SignerImpl) TransportWithKey(accessKeyID string, key Key, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{
+		signer:      s,
+		accessKeyID: accessKeyID,
+		key:         key,
+		next:        next,
+	}
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	if err := t.signer.SignRequestWithKey(r, t.accessKeyID, t.key); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(r)
+}
+
+// Middleware verifies the requests signed by Transport, and populates the
+// request context with the access key ID. Requests that fail the verification
+// are rejected with the status code from StatusCode.
+func (s *SignerImpl) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKeyID, err := s.verifyRequest(r)
+		if err != nil {
+			code := StatusCode(err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithAccessKeyID(r.Context(), accessKeyID)))
+	})
+}
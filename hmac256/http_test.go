@@ -0,0 +1,86 @@
+package hmac256_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/hmac256"
+)
+
+func TestTransportAndMiddleware(t *testing.T) {
+	signer := newSigner()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		accessKeyID, _ := hmac256.AccessKeyID(r.Context())
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s", accessKeyID, body)
+	}
+	ts := httptest.NewServer(signer.Middleware(http.HandlerFunc(handler)))
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: signer.Transport("access-key", "secret-key", nil),
+	}
+
+	req, err := http.NewRequest("POST", ts.URL+"/books?id=1", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "access-key hello" {
+		t.Fatalf("expected authenticated response, got %d %q", resp.StatusCode, body)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Fatalf("expected original request not to be modified")
+	}
+
+	resp, err = http.Post(ts.URL+"/books", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unsigned request to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestMiddlewareMaxBodySize(t *testing.T) {
+	client := newSigner()
+	server := hmac256.NewSigner(hmac256.Option{
+		Bearer:        "Acme",
+		ExpiresAfter:  time.Minute,
+		SignedHeaders: []string{"Content-Type"},
+		MaxBodySize:   16,
+	}, repository{"access-key": "secret-key"})
+
+	ts := httptest.NewServer(server.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer ts.Close()
+
+	c := &http.Client{
+		Transport: client.Transport("access-key", "secret-key", nil),
+	}
+	for body, code := range map[string]int{
+		strings.Repeat("a", 16): http.StatusOK,
+		strings.Repeat("a", 17): http.StatusRequestEntityTooLarge,
+	} {
+		resp, err := c.Post(ts.URL+"/books", "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Fatalf("expected status %d for %d bytes, got %d", code, len(body), resp.StatusCode)
+		}
+	}
+}
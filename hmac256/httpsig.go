@@ -0,0 +1,342 @@
+package hmac256
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// This file implements RFC 9421 HTTP Message Signatures, as an alternative to
+// the "<Bearer> <AccessKeyID>:<Signature>" Authorization header.
+
+const signatureLabel = "sig1"
+
+// DefaultComponents are the components covered by SignMessage when none are
+// given.
+var DefaultComponents = []string{"@method", "@target-uri", "content-digest"}
+
+// DefaultRequiredComponents are the components that the signatures must cover
+// when Option.RequiredComponents is not set, so that a signature cannot be
+// replayed against another method, URL or body. The content-digest is only
+// required for requests with a body.
+var DefaultRequiredComponents = []string{"@method", "@authority", "@path", "content-digest"}
+
+// signatureAlgorithms maps the algorithms to the RFC 9421 algorithm registry.
+// HMAC-SHA512 is not registered, so the alg parameter is omitted for it.
+var signatureAlgorithms = map[Algorithm]string{
+	HMACSHA256: "hmac-sha256",
+	Ed25519:    "ed25519",
+}
+
+// SignMessage signs the request according to RFC 9421, and sets the
+// Signature-Input and Signature headers. The components are the derived
+// components, e.g. "@method" and "@path", or lowercase header names. The
+// Content-Digest header is set if it is covered but not present.
+func (s *SignerImpl) SignMessage(r *http.Request, keyID string, key Key, components ...string) error {
+	if len(components) == 0 {
+		components = DefaultComponents
+	}
+	components = append([]string(nil), components...)
+	for i, c := range components {
+		components[i] = strings.ToLower(c)
+		if components[i] == "content-digest" && r.Header.Get("Content-Digest") == "" {
+			if err := SetContentDigest(r); err != nil {
+				return err
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, c := range components {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(quoteString(c))
+	}
+	b.WriteByte(')')
+	fmt.Fprintf(&b, ";created=%d", time.Now().Unix())
+	fmt.Fprintf(&b, ";nonce=%s", quoteString(newNonce()))
+	fmt.Fprintf(&b, ";keyid=%s", quoteString(keyID))
+	if alg, ok := signatureAlgorithms[key.Algorithm]; ok {
+		fmt.Fprintf(&b, ";alg=%s", quoteString(alg))
+	}
+	input := b.String()
+
+	base, err := signatureBase(r, components, input)
+	if err != nil {
+		return err
+	}
+	sig, err := key.signBytes([]byte(base))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Signature-Input", signatureLabel+"="+input)
+	r.Header.Set("Signature", signatureLabel+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+// VerifyMessage verifies the RFC 9421 signature of the request. The keyid
+// parameter is looked up in the KeyRepository, and the created parameter is
+// validated against Option.ExpiresAfter and Option.ClockSkew. The signature
+// must cover Option.RequiredComponents, and have a nonce if
+// Option.RequireNonce is set. When the request
+// has several signatures, it is valid if any of them is valid. The request
+// context is passed to the key lookup.
+func (s *SignerImpl) VerifyMessage(r *http.Request) error {
+	_, err := s.verifyMessage(r)
+	return err
+}
+
+// verifyMessage returns the key ID of the verified request.
+func (s *SignerImpl) verifyMessage(r *http.Request) (string, error) {
+	if r.Header.Get("Signature-Input") == "" {
+		return "", &HeaderError{Name: "Signature-Input", Err: ErrMissingHeader}
+	}
+	if r.Header.Get("Signature") == "" {
+		return "", &HeaderError{Name: "Signature", Err: ErrMissingHeader}
+	}
+	inputs, err := parseDictionary(strings.Join(r.Header["Signature-Input"], ","))
+	if err != nil {
+		return "", &HeaderError{Name: "Signature-Input", Err: ErrMalformedHeader}
+	}
+	signatures, err := parseDictionary(strings.Join(r.Header["Signature"], ","))
+	if err != nil {
+		return "", &HeaderError{Name: "Signature", Err: ErrMalformedHeader}
+	}
+
+	var firstErr error
+	for _, input := range inputs {
+		signature, ok := signatures.get(input.key)
+		if !ok {
+			continue
+		}
+		keyID, err := s.verifySignature(r.Context(), r, input.value, signature)
+		if err == nil {
+			return keyID, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = &HeaderError{Name: "Signature", Err: ErrMissingHeader}
+	}
+	return "", firstErr
+}
+
+func (s *SignerImpl) verifySignature(ctx context.Context, r *http.Request, input, signature string) (string, error) {
+	components, params, err := parseInnerList(input)
+	if err != nil {
+		return "", &HeaderError{Name: "Signature-Input", Err: ErrMalformedHeader}
+	}
+	sig, err := parseByteSequence(signature)
+	if err != nil {
+		return "", &HeaderError{Name: "Signature", Err: ErrMalformedHeader}
+	}
+
+	keyID, ok := params["keyid"]
+	if !ok {
+		return "", &HeaderError{Name: "Signature-Input", Err: ErrMissingHeader}
+	}
+	algorithm, err := parseSignatureAlgorithm(params)
+	if err != nil {
+		return "", err
+	}
+	if err := s.validateComponents(r, components); err != nil {
+		return "", err
+	}
+	keys, err := s.keys.LookupKeys(ctx, keyID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.validateSignatureParams(params); err != nil {
+		return "", err
+	}
+
+	var coversDigest bool
+	for _, c := range components {
+		coversDigest = coversDigest || c == "content-digest"
+	}
+	if coversDigest {
+		if err := verifyContentDigest(r, s.maxBodySize()); err != nil {
+			return "", err
+		}
+	}
+
+	base, err := signatureBase(r, components, input)
+	if err != nil {
+		return "", err
+	}
+	var valid bool
+	for _, key := range keys {
+		if algorithm != "" && key.Algorithm != algorithm {
+			continue
+		}
+		if key.verifyBytes([]byte(base), sig) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", ErrInvalidSignature
+	}
+
+	nonce, ok := params["nonce"]
+	if !ok {
+		if s.opt.RequireNonce {
+			return "", &HeaderError{Name: "Signature-Input", Err: ErrMissingHeader}
+		}
+		return keyID, nil
+	}
+	ok, err = s.nonceStore().Add(nonce, s.opt.ExpiresAfter+s.opt.ClockSkew)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrReplayed
+	}
+	return keyID, nil
+}
+
+// validateComponents checks that the covered components include the required
+// components. The @target-uri covers the other components of the URL.
+func (s *SignerImpl) validateComponents(r *http.Request, components []string) error {
+	covered := make(map[string]bool)
+	for _, c := range components {
+		covered[c] = true
+		switch c {
+		case "@target-uri":
+			covered["@scheme"] = true
+			covered["@authority"] = true
+			covered["@request-target"] = true
+			covered["@path"] = true
+			covered["@query"] = true
+		case "@request-target":
+			covered["@path"] = true
+			covered["@query"] = true
+		}
+	}
+
+	required := s.opt.RequiredComponents
+	if required == nil {
+		required = DefaultRequiredComponents
+	}
+	for _, c := range required {
+		c = strings.ToLower(c)
+		if c == "content-digest" && r.ContentLength == 0 {
+			continue
+		}
+		if !covered[c] {
+			return fmt.Errorf(`%w: "%s"`, ErrMissingComponent, c)
+		}
+	}
+	return nil
+}
+
+func (s *SignerImpl) validateSignatureParams(params map[string]string) error {
+	created, ok := params["created"]
+	if !ok {
+		return &HeaderError{Name: "Signature-Input", Err: ErrMissingHeader}
+	}
+	n, err := parseInteger(created)
+	if err != nil {
+		return &HeaderError{Name: "Signature-Input", Err: ErrMalformedHeader}
+	}
+	date := time.Unix(n, 0)
+	if time.Since(date) > s.opt.ExpiresAfter {
+		return ErrExpired
+	}
+	if time.Until(date) > s.opt.ClockSkew {
+		return ErrFutureDate
+	}
+
+	if expires, ok := params["expires"]; ok {
+		n, err := parseInteger(expires)
+		if err != nil {
+			return &HeaderError{Name: "Signature-Input", Err: ErrMalformedHeader}
+		}
+		if time.Now().After(time.Unix(n, 0)) {
+			return ErrExpired
+		}
+	}
+	return nil
+}
+
+// parseSignatureAlgorithm returns the algorithm of the alg parameter, or an
+// empty algorithm if the parameter is not present.
+func parseSignatureAlgorithm(params map[string]string) (Algorithm, error) {
+	alg, ok := params["alg"]
+	if !ok {
+		return "", nil
+	}
+	for algorithm, name := range signatureAlgorithms {
+		if name == alg {
+			return algorithm, nil
+		}
+	}
+	return "", fmt.Errorf(`%w: "%s"`, ErrUnsupportedAlgorithm, alg)
+}
+
+// signatureBase returns the RFC 9421 signature base:
+//
+//	"@method": POST
+//	"content-type": application/json
+//	"@signature-params": ("@method" "content-type");created=1618884473
+func signatureBase(r *http.Request, components []string, input string) (string, error) {
+	var b strings.Builder
+	for _, c := range components {
+		value, err := componentValue(r, c)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s: %s\n", quoteString(c), value)
+	}
+	fmt.Fprintf(&b, "%s: %s", quoteString("@signature-params"), input)
+	return b.String(), nil
+}
+
+func componentValue(r *http.Request, component string) (string, error) {
+	switch component {
+	case "@method":
+		return r.Method, nil
+	case "@target-uri":
+		return requestScheme(r) + "://" + requestHost(r) + r.URL.RequestURI(), nil
+	case "@authority":
+		return requestHost(r), nil
+	case "@scheme":
+		return requestScheme(r), nil
+	case "@request-target":
+		return r.URL.RequestURI(), nil
+	case "@path":
+		return canonicalPath(r.URL), nil
+	case "@query":
+		return "?" + r.URL.RawQuery, nil
+	}
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("%w: unsupported component %q", ErrMalformedHeader, component)
+	}
+
+	values, ok := r.Header[http.CanonicalHeaderKey(component)]
+	if !ok {
+		return "", &HeaderError{Name: http.CanonicalHeaderKey(component), Err: ErrMissingHeader}
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(result, ", "), nil
+}
+
+func requestScheme(r *http.Request) string {
+	if r.URL.Scheme != "" {
+		return strings.ToLower(r.URL.Scheme)
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,134 @@
+package hmac256
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+)
+
+// Algorithm identifies how the payload is signed.
+type Algorithm string
+
+const (
+	HMACSHA256 Algorithm = "HMAC-SHA256"
+	HMACSHA512 Algorithm = "HMAC-SHA512"
+	Ed25519    Algorithm = "Ed25519"
+)
+
+// Key is a signing key. For HMAC algorithms, Secret is the shared secret. For
+// Ed25519, Secret is the ed25519.PrivateKey when signing, and either the
+// ed25519.PublicKey or ed25519.PrivateKey when verifying.
+type Key struct {
+	Algorithm Algorithm
+	Secret    []byte
+}
+
+// KeyRepository returns the active keys of the access key ID. Returning more
+// than one key allows both the old and the new keys to verify during key
+// rotation. It should return ErrUnknownKey if the access key ID does not
+// exist.
+type KeyRepository interface {
+	LookupKeys(ctx context.Context, accessKeyID string) ([]Key, error)
+}
+
+// KeyRepositoryFunc is an adapter to allow the use of ordinary functions as
+// KeyRepository.
+type KeyRepositoryFunc func(ctx context.Context, accessKeyID string) ([]Key, error)
+
+func (fn KeyRepositoryFunc) LookupKeys(ctx context.Context, accessKeyID string) ([]Key, error) {
+	return fn(ctx, accessKeyID)
+}
+
+// secretKeyRepository adapts the Repository with a single HMAC-SHA256 secret.
+type secretKeyRepository struct {
+	repo Repository
+}
+
+func (r secretKeyRepository) LookupKeys(ctx context.Context, accessKeyID string) ([]Key, error) {
+	secretKey, err := r.repo.LookupSecretKey(accessKeyID)
+	if err != nil {
+		return nil, err
+	}
+	return []Key{{Algorithm: HMACSHA256, Secret: []byte(secretKey)}}, nil
+}
+
+func (k Key) sign(data string) (string, error) {
+	sig, err := k.signBytes([]byte(data))
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(sig), nil
+}
+
+func (k Key) verify(data, signature string) bool {
+	sig, err := base64.URLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return k.verifyBytes([]byte(data), sig)
+}
+
+func (k Key) signBytes(data []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case HMACSHA256:
+		return createMAC(sha256.New, k.Secret, data), nil
+	case HMACSHA512:
+		return createMAC(sha512.New, k.Secret, data), nil
+	case Ed25519:
+		if len(k.Secret) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("hmac256: invalid ed25519 private key size %d", len(k.Secret))
+		}
+		return ed25519.Sign(ed25519.PrivateKey(k.Secret), data), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, k.Algorithm)
+	}
+}
+
+func (k Key) verifyBytes(data, sig []byte) bool {
+	switch k.Algorithm {
+	case HMACSHA256, HMACSHA512:
+		expected, err := k.signBytes(data)
+		if err != nil {
+			return false
+		}
+		return hmac.Equal(expected, sig)
+	case Ed25519:
+		var pub ed25519.PublicKey
+		switch len(k.Secret) {
+		case ed25519.PublicKeySize:
+			pub = ed25519.PublicKey(k.Secret)
+		case ed25519.PrivateKeySize:
+			pub = ed25519.PrivateKey(k.Secret).Public().(ed25519.PublicKey)
+		default:
+			return false
+		}
+		return ed25519.Verify(pub, data, sig)
+	default:
+		return false
+	}
+}
+
+// verifyAny checks the signature against every key of the given algorithm.
+func verifyAny(keys []Key, algorithm Algorithm, data, signature string) error {
+	for _, key := range keys {
+		if key.Algorithm == algorithm && key.verify(data, signature) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func createHMAC(h func() hash.Hash, secret []byte, data string) string {
+	return base64.URLEncoding.EncodeToString(createMAC(h, secret, []byte(data)))
+}
+
+func createMAC(h func() hash.Hash, secret, data []byte) []byte {
+	mac := hmac.New(h, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
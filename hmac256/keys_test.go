@@ -0,0 +1,94 @@
+package hmac256_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/hmac256"
+)
+
+type contextKey string
+
+func TestKeyRotation(t *testing.T) {
+	var (
+		oldKey = hmac256.Key{Algorithm: hmac256.HMACSHA256, Secret: []byte("old")}
+		newKey = hmac256.Key{Algorithm: hmac256.HMACSHA512, Secret: []byte("new")}
+	)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey := hmac256.Key{Algorithm: hmac256.Ed25519, Secret: priv}
+
+	signer := hmac256.NewSignerWithKeys(hmac256.Option{
+		Bearer:       "Acme",
+		ExpiresAfter: time.Minute,
+	}, hmac256.KeyRepositoryFunc(func(ctx context.Context, accessKeyID string) ([]hmac256.Key, error) {
+		if ctx.Value(contextKey("tenant")) != "acme" {
+			t.Errorf("expected context to be passed to the lookup")
+		}
+		if accessKeyID != "access-key" {
+			return nil, hmac256.ErrUnknownKey
+		}
+		return []hmac256.Key{
+			oldKey,
+			newKey,
+			{Algorithm: hmac256.Ed25519, Secret: pub},
+		}, nil
+	}))
+	ctx := context.WithValue(context.Background(), contextKey("tenant"), "acme")
+
+	for _, key := range []hmac256.Key{oldKey, newKey, edKey} {
+		t.Run(string(key.Algorithm), func(t *testing.T) {
+			header := signer.ConvertMapToHeaders(map[string]interface{}{"user": "john"})
+			signature, err := signer.SignHeadersWithKey(key, header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header.Set("Authorization", signer.NewAuthorizationHeaderWithAlgorithm(key.Algorithm, "access-key", signature))
+			if err := signer.ValidateHeaderContext(ctx, header); err != nil {
+				t.Fatalf("expected valid header, got %v", err)
+			}
+
+			r := httptest.NewRequest("GET", "http://example.com/books", nil).WithContext(ctx)
+			if err := signer.SignRequestWithKey(r, "access-key", key); err != nil {
+				t.Fatal(err)
+			}
+			if err := signer.VerifyRequest(r); err != nil {
+				t.Fatalf("expected valid request, got %v", err)
+			}
+		})
+	}
+
+	t.Run("retired key", func(t *testing.T) {
+		header := signer.ConvertMapToHeaders(map[string]interface{}{"user": "john"})
+		signature := signer.SignHeaders("retired", header)
+		header.Set("Authorization", signer.NewAuthorizationHeader("access-key", signature))
+		if err := signer.ValidateHeaderContext(ctx, header); !errors.Is(err, hmac256.ErrInvalidSignature) {
+			t.Fatalf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("algorithm mismatch", func(t *testing.T) {
+		// A HMAC-SHA256 signature claimed as HMAC-SHA512.
+		header := signer.ConvertMapToHeaders(map[string]interface{}{"user": "john"})
+		signature := signer.SignHeaders("old", header)
+		header.Set("Authorization", signer.NewAuthorizationHeaderWithAlgorithm(hmac256.HMACSHA512, "access-key", signature))
+		if err := signer.ValidateHeaderContext(ctx, header); !errors.Is(err, hmac256.ErrInvalidSignature) {
+			t.Fatalf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		header := signer.ConvertMapToHeaders(map[string]interface{}{"user": "john"})
+		header.Set("Authorization", "Acme HMAC-MD5 access-key:sig")
+		if err := signer.ValidateHeaderContext(ctx, header); !errors.Is(err, hmac256.ErrUnsupportedAlgorithm) {
+			t.Fatalf("expected ErrUnsupportedAlgorithm, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package hmac256
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/pkg/ttlmap"
+)
+
+// NonceStore keeps track of the nonces that have been used.
+type NonceStore interface {
+	// Add stores the nonce for the given ttl. It returns false if the nonce
+	// has already been used.
+	Add(nonce string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore is an in-memory NonceStore, suitable for a single
+// instance.
+type MemoryNonceStore struct {
+	m *ttlmap.TTLMap
+}
+
+// NewMemoryNonceStore returns a new in-memory nonce store, and a function to
+// stop the cleanup of expired nonces.
+func NewMemoryNonceStore() (*MemoryNonceStore, func()) {
+	m, cancel := ttlmap.New()
+	return &MemoryNonceStore{m: m}, cancel
+}
+
+func (s *MemoryNonceStore) Add(nonce string, ttl time.Duration) (bool, error) {
+	return s.m.SetNX(nonce, struct{}{}, ttl), nil
+}
+
+var (
+	defaultNonceStoreOnce sync.Once
+	defaultNonceStore     *MemoryNonceStore
+)
+
+// nonceStore returns Option.NonceStore, or the in-memory store shared by the
+// signers without one, so that creating signers does not start a cleanup for
+// each of them.
+func (s *SignerImpl) nonceStore() NonceStore {
+	if s.opt.NonceStore != nil {
+		return s.opt.NonceStore
+	}
+	defaultNonceStoreOnce.Do(func() {
+		defaultNonceStore, _ = NewMemoryNonceStore()
+	})
+	return defaultNonceStore
+}
+
+func newNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,26 @@
+package hmac256
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisNonceStore is a NonceStore shared by multiple instances.
+type RedisNonceStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisNonceStore returns a new redis nonce store.
+func NewRedisNonceStore(client *redis.Client) *RedisNonceStore {
+	return &RedisNonceStore{
+		client: client,
+		prefix: "hmac256:nonce:",
+	}
+}
+
+func (s *RedisNonceStore) Add(nonce string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(context.Background(), s.prefix+nonce, 1, ttl).Result()
+}
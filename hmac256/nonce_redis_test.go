@@ -0,0 +1,35 @@
+package hmac256_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/alextanhongpin/pkg/hmac256"
+)
+
+func TestRedisNonceStore(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	store := hmac256.NewRedisNonceStore(client)
+	if ok, err := store.Add("nonce", time.Minute); err != nil || !ok {
+		t.Fatalf("expected new nonce to be added, got %t %v", ok, err)
+	}
+	if ok, err := store.Add("nonce", time.Minute); err != nil || ok {
+		t.Fatalf("expected used nonce to be rejected, got %t %v", ok, err)
+	}
+
+	s.FastForward(2 * time.Minute)
+	if ok, err := store.Add("nonce", time.Minute); err != nil || !ok {
+		t.Fatalf("expected expired nonce to be added, got %t %v", ok, err)
+	}
+}
@@ -0,0 +1,195 @@
+package hmac256
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// SignRequest signs the canonical form of the request, which covers the
+// method, path, sorted query, host, the X-<Bearer>-* headers, the headers in
+// Option.SignedHeaders and the SHA-256 digest of the body, and sets the
+// Authorization header. The date, nonce and body digest headers are set if not
+// present.
+func (s *SignerImpl) SignRequest(r *http.Request, accessKeyID, secretKey string) error {
+	return s.SignRequestWithKey(r, accessKeyID, Key{Algorithm: HMACSHA256, Secret: []byte(secretKey)})
+}
+
+// SignRequestWithKey is like SignRequest, but signs with the algorithm of the
+// key.
+func (s *SignerImpl) SignRequestWithKey(r *http.Request, accessKeyID string, key Key) error {
+	digest, err := hashBody(r, 0)
+	if err != nil {
+		return err
+	}
+	s.setDefaultHeaders(r.Header)
+	r.Header.Set(s.headerContentSHA256, digest)
+
+	signature, err := key.sign(s.canonicalRequest(r, digest))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", s.NewAuthorizationHeaderWithAlgorithm(key.Algorithm, accessKeyID, signature))
+	return nil
+}
+
+// VerifyRequest checks the Authorization header of the request signed with
+// SignRequest. The request context is passed to the key lookup.
+func (s *SignerImpl) VerifyRequest(r *http.Request) error {
+	_, err := s.verifyRequest(r)
+	return err
+}
+
+// verifyRequest returns the access key ID of the verified request.
+func (s *SignerImpl) verifyRequest(r *http.Request) (string, error) {
+	algorithm, accessKey, signature, err := s.parseAuthorization(r.Header)
+	if err != nil {
+		return "", err
+	}
+	keys, err := s.keys.LookupKeys(r.Context(), accessKey)
+	if err != nil {
+		return "", err
+	}
+	if err := s.validateFreshness(r.Header); err != nil {
+		return "", err
+	}
+
+	digest, err := hashBody(r, s.maxBodySize())
+	if err != nil {
+		return "", err
+	}
+	if subtle.ConstantTimeCompare([]byte(digest), []byte(r.Header.Get(s.headerContentSHA256))) != 1 {
+		return "", fmt.Errorf("%w: body digest mismatch", ErrInvalidSignature)
+	}
+
+	if err := verifyAny(keys, algorithm, s.canonicalRequest(r, digest), signature); err != nil {
+		return "", err
+	}
+	if err := s.useNonce(r.Header); err != nil {
+		return "", err
+	}
+	return accessKey, nil
+}
+
+// canonicalRequest returns the canonical form of the request:
+//
+//	METHOD
+//	/escaped/path
+//	sorted=query&string=
+//	host:example.com
+//	x-bearer-date:1600000000
+//	host;x-bearer-date
+//	hex(sha256(body))
+func (s *SignerImpl) canonicalRequest(r *http.Request, digest string) string {
+	headers := map[string]string{
+		"host": requestHost(r),
+	}
+	for key, values := range r.Header {
+		if hasPrefixFold(key, s.headerPrefix) {
+			headers[strings.ToLower(key)] = canonicalHeaderValue(values)
+		}
+	}
+	for _, key := range s.opt.SignedHeaders {
+		headers[strings.ToLower(key)] = canonicalHeaderValue(r.Header[http.CanonicalHeaderKey(key)])
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(r.Method + "\n")
+	b.WriteString(canonicalPath(r.URL) + "\n")
+	b.WriteString(canonicalQuery(r.URL.Query()) + "\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s:%s\n", name, headers[name])
+	}
+	b.WriteString(strings.Join(names, ";") + "\n")
+	b.WriteString(digest)
+	return b.String()
+}
+
+// hashBody returns the hex encoded SHA-256 digest of the body.
+func hashBody(r *http.Request, maxSize int64) (string, error) {
+	body, err := readBody(r, maxSize)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readBody reads the body, and replaces the body so that it can be read again.
+// It returns ErrBodyTooLarge if the body is larger than maxSize. A
+// non-positive maxSize does not limit the body, e.g. for the requests to sign.
+func readBody(r *http.Request, maxSize int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	if maxSize > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, maxSize)
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxErr.Limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+func requestHost(r *http.Request) string {
+	if r.Host != "" {
+		return strings.ToLower(r.Host)
+	}
+	return strings.ToLower(r.URL.Host)
+}
+
+func canonicalPath(u *url.URL) string {
+	if p := u.EscapedPath(); p != "" {
+		return p
+	}
+	return "/"
+}
+
+// canonicalQuery sorts the query by key, then by value.
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func canonicalHeaderValue(values []string) string {
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = strings.Join(strings.Fields(v), " ")
+	}
+	return strings.Join(result, ",")
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
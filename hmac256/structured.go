@@ -0,0 +1,202 @@
+package hmac256
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// This file implements the subset of RFC 8941 structured field values needed
+// by RFC 9421 and RFC 9530.
+
+var errMalformedStructuredField = errors.New("malformed structured field")
+
+type member struct {
+	key, value string
+}
+
+type dictionary []member
+
+// get returns the value of the key. The last value wins for duplicate keys.
+func (d dictionary) get(key string) (string, bool) {
+	for i := len(d) - 1; i >= 0; i-- {
+		if d[i].key == key {
+			return d[i].value, true
+		}
+	}
+	return "", false
+}
+
+// parseDictionary splits the dictionary into its members without parsing the
+// member values.
+func parseDictionary(s string) (dictionary, error) {
+	var (
+		d       dictionary
+		start   int
+		inQuote bool
+	)
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			switch c := s[i]; {
+			case inQuote && c == '\\':
+				i++
+				continue
+			case c == '"':
+				inQuote = !inQuote
+				continue
+			case inQuote || c != ',':
+				continue
+			}
+		}
+		if inQuote {
+			return nil, errMalformedStructuredField
+		}
+		m, err := parseMember(strings.Trim(s[start:i], " \t"))
+		if err != nil {
+			return nil, err
+		}
+		d = append(d, m)
+		start = i + 1
+	}
+	return d, nil
+}
+
+func parseMember(s string) (member, error) {
+	key, value := s, "?1"
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		key, value = s[:i], s[i+1:]
+	}
+	if !isKey(key) || value == "" {
+		return member{}, errMalformedStructuredField
+	}
+	return member{key: key, value: value}, nil
+}
+
+func isKey(s string) bool {
+	if s == "" || !(s[0] == '*' || 'a' <= s[0] && s[0] <= 'z') {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("_-.*", c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseInnerList parses an inner list of strings with parameters, e.g.
+// ("@method" "content-type");created=1618884473;keyid="test-key". Parameters
+// on the items are not supported.
+func parseInnerList(s string) (items []string, params map[string]string, err error) {
+	if s == "" || s[0] != '(' {
+		return nil, nil, errMalformedStructuredField
+	}
+	s = s[1:]
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return nil, nil, errMalformedStructuredField
+		}
+		if s[0] == ')' {
+			s = s[1:]
+			break
+		}
+		var item string
+		item, s, err = parseString(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		if s != "" && s[0] != ' ' && s[0] != ')' {
+			return nil, nil, errMalformedStructuredField
+		}
+		items = append(items, item)
+	}
+
+	params, err = parseParameters(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return items, params, nil
+}
+
+// parseParameters parses the parameters, e.g. ;created=1618884473;keyid="k".
+// String values are unquoted, and other values are returned as is.
+func parseParameters(s string) (map[string]string, error) {
+	params := make(map[string]string)
+	for s != "" {
+		if s[0] != ';' {
+			return nil, errMalformedStructuredField
+		}
+		s = strings.TrimLeft(s[1:], " ")
+
+		i := 0
+		for i < len(s) && s[i] != '=' && s[i] != ';' {
+			i++
+		}
+		key := s[:i]
+		if !isKey(key) {
+			return nil, errMalformedStructuredField
+		}
+		s = s[i:]
+
+		value := "?1"
+		if s != "" && s[0] == '=' {
+			s = s[1:]
+			var err error
+			if s != "" && s[0] == '"' {
+				value, s, err = parseString(s)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				j := strings.IndexByte(s, ';')
+				if j < 0 {
+					j = len(s)
+				}
+				value, s = s[:j], s[j:]
+			}
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
+// parseString parses the quoted string at the start of s, and returns the
+// remaining input.
+func parseString(s string) (string, string, error) {
+	if s == "" || s[0] != '"' {
+		return "", "", errMalformedStructuredField
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			i++
+			if i == len(s) || (s[i] != '"' && s[i] != '\\') {
+				return "", "", errMalformedStructuredField
+			}
+			b.WriteByte(s[i])
+		case '"':
+			return b.String(), s[i+1:], nil
+		default:
+			if c < 0x20 || c > 0x7e {
+				return "", "", errMalformedStructuredField
+			}
+			b.WriteByte(c)
+		}
+	}
+	return "", "", errMalformedStructuredField
+}
+
+func quoteString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func parseInteger(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || len(s) > 15 {
+		return 0, errMalformedStructuredField
+	}
+	return n, nil
+}
@@ -3,60 +3,75 @@ package observable
 import (
 	"errors"
 	"fmt"
-	"log"
+	"hash/fnv"
 	"sync"
 	"time"
 )
 
+var (
+	// ErrHandlerPanic is reported when the handler panics.
+	ErrHandlerPanic = errors.New("observable: handler panicked")
+
+	// ErrHandlerTimeout is reported when the handler exceeds the timeout.
+	ErrHandlerTimeout = errors.New("observable: handler timeout exceeded")
+)
+
 type AsyncObservable struct {
-	wg sync.WaitGroup
-	// We can probably extract this into a separate data structure.
-	sync.RWMutex
-	events map[Event][]Action
+	wg   sync.WaitGroup
+	subs *registry
+	opts *options
+
+	// Ensure the workers are started once.
+	start sync.Once
 
 	// Ensure the write channel is closed once.
 	once sync.Once
 	quit chan interface{}
 
-	// Ensure the read channel is closed once.
-	onceCh sync.Once
-	ch     chan Message
+	// Guards the read channels from being closed while sending.
+	mu  sync.RWMutex
+	chs []chan Message
 }
 
-func NewAsync(n int) *AsyncObservable {
-	events := make(map[Event][]Action)
-	return &AsyncObservable{
-		events: events,
-		quit:   make(chan interface{}),
-		ch:     make(chan Message, n),
+// NewAsync returns a new AsyncObservable, where n is the buffer size of each
+// worker.
+func NewAsync(n int, opts ...Option) *AsyncObservable {
+	o := &AsyncObservable{
+		subs: newRegistry(),
+		opts: newOptions(opts),
+		quit: make(chan interface{}),
+	}
+	o.chs = make([]chan Message, o.opts.workers)
+	for i := range o.chs {
+		o.chs[i] = make(chan Message, n)
 	}
+	return o
 }
 
-func (o *AsyncObservable) On(event Event, fn Action) {
-	o.Lock()
-	_, exist := o.events[event]
-	if !exist {
-		o.events[event] = make([]Action, 0)
-	}
-	o.events[event] = append(o.events[event], fn)
-	o.Unlock()
+func (o *AsyncObservable) On(event Event, fn Action, opts ...SubscribeOption) func() {
+	return o.subs.add(event, fn, false, opts...)
+}
+
+func (o *AsyncObservable) Once(event Event, fn Action, opts ...SubscribeOption) func() {
+	return o.subs.add(event, fn, true, opts...)
 }
 
 func (o *AsyncObservable) Emit(event Event, params interface{}) error {
-	// Why do we need two select here? This is to ensure only the sole case here (o.quit) does not compete with the o.ch. In a situation where both o.quit and o.ch matches the select, if the o.ch is closed and a message is sent to o.ch, it will panic.
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	// Check first, so that the quit case does not compete with the send.
 	select {
 	case <-o.quit:
-		o.onceCh.Do(func() {
-			close(o.ch)
-		})
 		return errors.New("channel closed")
 	default:
 	}
 
+	ch := o.chs[o.worker(event, params)]
 	select {
 	case <-o.quit:
 		return errors.New("channel closed")
-	case o.ch <- Message{event, params}:
+	case ch <- Message{event, params}:
 		return nil
 	case <-time.After(5 * time.Second):
 		return errors.New("timeout exceeded")
@@ -67,32 +82,92 @@ func (o *AsyncObservable) Emit(event Event, params interface{}) error {
 // channels. The unread messages will be flushed before the process completes.
 func (o *AsyncObservable) Stop() {
 	o.once.Do(func() {
+		// Unblock the pending Emit before closing the read channels.
 		close(o.quit)
+
+		o.mu.Lock()
+		for _, ch := range o.chs {
+			close(ch)
+		}
+		o.mu.Unlock()
 	})
 	o.wg.Wait()
 }
 
-// Start
+// Start starts the workers. Subsequent calls have no effect.
 func (o *AsyncObservable) Start() {
-	o.wg.Add(1)
-	go func() {
-		defer o.wg.Done()
-		for evt := range o.ch {
-			// Still need to lock to ensure there's no data race.
-			o.RLock()
-			fns, exist := o.events[evt.event]
-			o.RUnlock()
-			if !exist {
-				log.Println(fmt.Errorf(`event "%s" is not registered`, evt.event))
-			}
-			for _, fn := range fns {
-				if err := fn(evt.params); err != nil {
-					log.Println(err)
+	o.start.Do(func() {
+		o.wg.Add(len(o.chs))
+		for _, ch := range o.chs {
+			go func(ch chan Message) {
+				defer o.wg.Done()
+				for evt := range ch {
+					o.handle(evt)
 				}
-			}
+			}(ch)
+		}
+	})
+}
+
+func (o *AsyncObservable) worker(event Event, params interface{}) int {
+	if len(o.chs) == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(o.opts.partition(event, params)))
+	return int(h.Sum32() % uint32(len(o.chs)))
+}
+
+func (o *AsyncObservable) handle(evt Message) {
+	subs := o.subs.match(evt.event)
+	if len(subs) == 0 {
+		o.opts.onError(evt.event, fmt.Errorf(`event "%s" is not registered`, evt.event))
+	}
+	for _, s := range subs {
+		if !o.subs.claim(s) {
+			continue
+		}
+		n, err := s.retry.do(func() error {
+			return o.call(evt.event, s, evt.params)
+		})
+		if err != nil {
+			o.opts.onError(evt.event, err)
+			o.opts.exhausted(evt.event, evt.params, n, err)
 		}
+	}
+}
+
+// call runs the handler with the timeout of the subscription, if any.
+func (o *AsyncObservable) call(event Event, s *subscription, params interface{}) error {
+	if s.timeout <= 0 {
+		return o.dispatch(event, s, params)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- o.dispatch(event, s, params)
 	}()
 
+	t := time.NewTimer(s.timeout)
+	defer t.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-t.C:
+		return fmt.Errorf(`%w: event "%s"`, ErrHandlerTimeout, event)
+	}
+}
+
+// dispatch recovers the panic of the handler, so that it does not stop the
+// worker.
+func (o *AsyncObservable) dispatch(event Event, s *subscription, params interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf(`%w: event "%s": %v`, ErrHandlerPanic, event, r)
+		}
+	}()
+	return s.fn(params)
 }
 
 const (
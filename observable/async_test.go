@@ -0,0 +1,215 @@
+package observable_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/observable"
+)
+
+// captureLog returns the log output written until the test ends.
+func captureLog(t *testing.T) *syncBuffer {
+	var buf syncBuffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+	})
+	return &buf
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAsyncStopFlushes(t *testing.T) {
+	o := observable.NewAsync(100, observable.WithWorkers(4))
+
+	var mu sync.Mutex
+	var n int
+	o.On("user.created", func(params interface{}) error {
+		mu.Lock()
+		n++
+		mu.Unlock()
+		return nil
+	})
+	o.Start()
+	o.Start()
+	for i := 0; i < 100; i++ {
+		if err := o.Emit("user.created", i); err != nil {
+			t.Fatalf("emit failed: %v", err)
+		}
+	}
+	o.Stop()
+	o.Stop()
+	if n != 100 {
+		t.Fatalf("expected %d events to be handled, got %d", 100, n)
+	}
+	if err := o.Emit("user.created", 0); err == nil {
+		t.Fatal("expected error after stop")
+	}
+}
+
+func TestAsyncPartitionOrdering(t *testing.T) {
+	type event struct {
+		user string
+		seq  int
+	}
+	o := observable.NewAsync(100,
+		observable.WithWorkers(4),
+		observable.WithPartitionKey(func(_ observable.Event, params interface{}) string {
+			return params.(event).user
+		}),
+	)
+
+	var mu sync.Mutex
+	got := make(map[string][]int)
+	o.On("user.updated", func(params interface{}) error {
+		e := params.(event)
+		mu.Lock()
+		got[e.user] = append(got[e.user], e.seq)
+		mu.Unlock()
+		return nil
+	})
+	o.Start()
+
+	users := []string{"alice", "bob", "carol", "dave", "erin"}
+	for i := 0; i < 50; i++ {
+		for _, user := range users {
+			if err := o.Emit("user.updated", event{user, i}); err != nil {
+				t.Fatalf("emit failed: %v", err)
+			}
+		}
+	}
+	o.Stop()
+
+	for _, user := range users {
+		if len(got[user]) != 50 {
+			t.Fatalf("expected %d events for %s, got %d", 50, user, len(got[user]))
+		}
+		for i, seq := range got[user] {
+			if seq != i {
+				t.Fatalf("expected events of %s in order, got %v", user, got[user])
+			}
+		}
+	}
+}
+
+func TestAsyncRecoversPanic(t *testing.T) {
+	logs := captureLog(t)
+	o := observable.NewAsync(10)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	o.On("user.created", func(params interface{}) error {
+		if params == "panic" {
+			panic("boom")
+		}
+		return nil
+	})
+	o.On("user.created", func(params interface{}) error {
+		wg.Done()
+		return nil
+	})
+	o.Start()
+	defer o.Stop()
+
+	for _, msg := range []string{"panic", "ok"} {
+		if err := o.Emit("user.created", msg); err != nil {
+			t.Fatalf("emit failed: %v", err)
+		}
+	}
+	wg.Wait()
+	if !strings.Contains(logs.String(), observable.ErrHandlerPanic.Error()) {
+		t.Fatalf("expected panic to be reported, got %q", logs.String())
+	}
+}
+
+func TestAsyncHandlerTimeout(t *testing.T) {
+	logs := captureLog(t)
+	o := observable.NewAsync(10)
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	o.On("user.created", func(params interface{}) error {
+		<-release
+		return nil
+	}, observable.WithHandlerTimeout(10*time.Millisecond))
+	o.On("user.created", func(params interface{}) error {
+		close(done)
+		return nil
+	})
+	o.Start()
+	defer o.Stop()
+	defer close(release)
+
+	if err := o.Emit("user.created", nil); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected slow handler not to block the next handler")
+	}
+	if !strings.Contains(logs.String(), observable.ErrHandlerTimeout.Error()) {
+		t.Fatalf("expected timeout to be reported, got %q", logs.String())
+	}
+}
+
+func TestAsyncHandlerTimeoutNoRetry(t *testing.T) {
+	dead := make(chan observable.DeadLetter, 1)
+	o := observable.NewAsync(10,
+		observable.WithErrorHandler(func(event observable.Event, err error) {}),
+		observable.WithDeadLetter(func(msg observable.DeadLetter) {
+			dead <- msg
+		}),
+	)
+
+	release := make(chan struct{})
+	var attempts int32
+	o.On("user.created", func(params interface{}) error {
+		atomic.AddInt32(&attempts, 1)
+		<-release
+		return nil
+	},
+		observable.WithHandlerTimeout(10*time.Millisecond),
+		observable.WithRetry(observable.RetryPolicy{MaxAttempts: 3}),
+	)
+	o.Start()
+	defer o.Stop()
+	defer close(release)
+
+	if err := o.Emit("user.created", nil); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+	select {
+	case msg := <-dead:
+		if !errors.Is(msg.Err, observable.ErrHandlerTimeout) || msg.Attempts != 1 {
+			t.Fatalf("expected a single timed out attempt, got %v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected timed out event to be dead-lettered")
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Fatalf("expected %d attempts, got %d", 1, n)
+	}
+}
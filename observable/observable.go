@@ -2,7 +2,11 @@ package observable
 
 // Observer represents the observer interface.
 type Observer interface {
-	On(event Event, fn Action)
+	// On registers the action for the event, or the events matching the
+	// pattern, e.g. "user.*". It returns the function to unsubscribe.
+	On(event Event, fn Action, opts ...SubscribeOption) func()
+	// Once is like On, but the action is unsubscribed after the first event.
+	Once(event Event, fn Action, opts ...SubscribeOption) func()
 	Emit(event Event, params interface{}) error
 	Start()
 	Stop()
@@ -0,0 +1,86 @@
+package observable
+
+import "log"
+
+// Option configures the observers.
+type Option func(*options)
+
+type options struct {
+	workers    int
+	partition  func(event Event, params interface{}) string
+	onError    func(event Event, err error)
+	deadLetter func(DeadLetter)
+	joinErrors bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		workers: 1,
+		partition: func(event Event, params interface{}) string {
+			return event.String()
+		},
+		onError: func(event Event, err error) {
+			log.Println(err)
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithWorkers sets the number of workers handling the events of the
+// AsyncObservable. Defaults to 1.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.workers = n
+		}
+	}
+}
+
+// WithPartitionKey sets the key that partitions the events across the
+// workers of the AsyncObservable. Events with the same key are handled in
+// order by the same worker. Defaults to the event name. Use Payload to get
+// the payloads published on a Topic.
+func WithPartitionKey(fn func(event Event, params interface{}) string) Option {
+	return func(o *options) {
+		o.partition = fn
+	}
+}
+
+// WithErrorHandler sets the callback for the handler errors of the
+// AsyncObservable. Defaults to the log package.
+func WithErrorHandler(fn func(event Event, err error)) Option {
+	return func(o *options) {
+		o.onError = fn
+	}
+}
+
+// WithDeadLetter sets the sink for the events whose handler failed after
+// exhausting the retries.
+func WithDeadLetter(fn func(DeadLetter)) Option {
+	return func(o *options) {
+		o.deadLetter = fn
+	}
+}
+
+// WithJoinErrors runs all the handlers of the SyncObservable, instead of
+// stopping at the first error, and returns the joined errors.
+func WithJoinErrors() Option {
+	return func(o *options) {
+		o.joinErrors = true
+	}
+}
+
+// exhausted sends the failed event to the dead-letter sink, if any.
+func (o *options) exhausted(event Event, params interface{}, attempts int, err error) {
+	if o.deadLetter != nil {
+		o.deadLetter(DeadLetter{
+			Event:    event,
+			Params:   params,
+			Err:      err,
+			Attempts: attempts,
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package observable
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Wildcard matches all events. Events ending with ".*" match all events with
+// the prefix, e.g. "user.*" matches "user.login" and "user.profile.updated".
+const Wildcard = Event("*")
+
+type subscription struct {
+	id      uint64
+	event   Event
+	fn      Action
+	once    bool
+	done    int32
+	retry   RetryPolicy
+	timeout time.Duration
+}
+
+// SubscribeOption configures the subscription.
+type SubscribeOption func(*subscription)
+
+// WithRetry retries the failed action with the policy.
+func WithRetry(policy RetryPolicy) SubscribeOption {
+	return func(s *subscription) {
+		s.retry = policy
+	}
+}
+
+// WithHandlerTimeout sets the duration the action may run on the worker of the
+// AsyncObservable before the worker moves on. The action is not cancelled, it
+// keeps running in the background until it returns, so the next events of
+// the partition may be handled concurrently with it, and are no longer
+// ordered after it. A timed out action is reported with ErrHandlerTimeout
+// and is not retried, since it may still complete. The SyncObservable
+// ignores the timeout.
+func WithHandlerTimeout(d time.Duration) SubscribeOption {
+	return func(s *subscription) {
+		s.timeout = d
+	}
+}
+
+// claim reports whether the subscription should handle the event. Once
+// subscriptions are only claimed by the first event, even when events are
+// dispatched concurrently.
+func (s *subscription) claim() bool {
+	if !s.once {
+		return true
+	}
+	return atomic.CompareAndSwapInt32(&s.done, 0, 1)
+}
+
+// registry holds the subscriptions of the observers.
+type registry struct {
+	sync.RWMutex
+	nextID   uint64
+	events   map[Event][]*subscription
+	patterns map[Event][]*subscription
+}
+
+func newRegistry() *registry {
+	return &registry{
+		events:   make(map[Event][]*subscription),
+		patterns: make(map[Event][]*subscription),
+	}
+}
+
+// add registers the action, and returns the function to remove it.
+func (r *registry) add(event Event, fn Action, once bool, opts ...SubscribeOption) func() {
+	s := &subscription{event: event, fn: fn, once: once}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	r.Lock()
+	r.nextID++
+	s.id = r.nextID
+	if isPattern(event) {
+		r.patterns[event] = append(r.patterns[event], s)
+	} else {
+		r.events[event] = append(r.events[event], s)
+	}
+	r.Unlock()
+
+	var unsubscribe sync.Once
+	return func() {
+		unsubscribe.Do(func() {
+			r.remove(s)
+		})
+	}
+}
+
+func (r *registry) remove(s *subscription) {
+	r.Lock()
+	defer r.Unlock()
+
+	subs := r.events
+	if isPattern(s.event) {
+		subs = r.patterns
+	}
+	for i, sub := range subs[s.event] {
+		if sub == s {
+			// Copy, since the previous slice may still be dispatched.
+			rest := make([]*subscription, 0, len(subs[s.event])-1)
+			rest = append(rest, subs[s.event][:i]...)
+			rest = append(rest, subs[s.event][i+1:]...)
+			subs[s.event] = rest
+			break
+		}
+	}
+	if len(subs[s.event]) == 0 {
+		delete(subs, s.event)
+	}
+}
+
+// match returns the subscriptions of the event, including the matching
+// patterns, in the order of subscription.
+func (r *registry) match(event Event) []*subscription {
+	r.RLock()
+	defer r.RUnlock()
+
+	result := append([]*subscription(nil), r.events[event]...)
+	if len(r.patterns) == 0 {
+		return result
+	}
+	for pattern, subs := range r.patterns {
+		if matchPattern(pattern, event) {
+			result = append(result, subs...)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].id < result[j].id
+	})
+	return result
+}
+
+// claim reports whether the subscription should handle the event, and
+// removes the once subscriptions.
+func (r *registry) claim(s *subscription) bool {
+	if !s.claim() {
+		return false
+	}
+	if s.once {
+		r.remove(s)
+	}
+	return true
+}
+
+func isPattern(event Event) bool {
+	return event == Wildcard || strings.HasSuffix(string(event), ".*")
+}
+
+func matchPattern(pattern, event Event) bool {
+	if pattern == Wildcard {
+		return true
+	}
+	return strings.HasPrefix(string(event), strings.TrimSuffix(string(pattern), "*"))
+}
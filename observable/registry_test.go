@@ -0,0 +1,144 @@
+package observable_test
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alextanhongpin/pkg/observable"
+)
+
+func TestUnsubscribe(t *testing.T) {
+	o := observable.NewSync()
+
+	var calls []string
+	unsubscribe := o.On("user.created", func(params interface{}) error {
+		calls = append(calls, "first")
+		return nil
+	})
+	o.On("user.created", func(params interface{}) error {
+		calls = append(calls, "second")
+		return nil
+	})
+
+	if err := o.Emit("user.created", nil); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+	unsubscribe()
+	unsubscribe()
+	if err := o.Emit("user.created", nil); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+	if want := []string{"first", "second", "second"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("expected %v, got %v", want, calls)
+	}
+}
+
+func TestUnsubscribeLastHandler(t *testing.T) {
+	o := observable.NewSync()
+	unsubscribe := o.On("user.created", func(params interface{}) error {
+		return nil
+	})
+	unsubscribe()
+	if err := o.Emit("user.created", nil); err == nil {
+		t.Fatal("expected error for event without handlers")
+	}
+}
+
+func TestOnce(t *testing.T) {
+	o := observable.NewSync()
+
+	var n int
+	o.Once("user.created", func(params interface{}) error {
+		n++
+		return nil
+	})
+	if err := o.Emit("user.created", nil); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+	if err := o.Emit("user.created", nil); err == nil {
+		t.Fatal("expected error for event without handlers")
+	}
+	if n != 1 {
+		t.Fatalf("expected once handler to be called %d time, got %d", 1, n)
+	}
+}
+
+func TestOnceConcurrent(t *testing.T) {
+	o := observable.NewSync()
+
+	var n int32
+	o.Once("user.created", func(params interface{}) error {
+		atomic.AddInt32(&n, 1)
+		return nil
+	})
+	o.On("user.created", func(params interface{}) error {
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = o.Emit("user.created", nil)
+		}()
+	}
+	wg.Wait()
+	if n != 1 {
+		t.Fatalf("expected once handler to be called %d time, got %d", 1, n)
+	}
+}
+
+func TestWildcard(t *testing.T) {
+	o := observable.NewSync()
+
+	var calls []string
+	record := func(name string) observable.Action {
+		return func(params interface{}) error {
+			calls = append(calls, name+":"+params.(string))
+			return nil
+		}
+	}
+	o.On("user.*", record("user.*"))
+	o.On("user.created", record("user.created"))
+	o.On(observable.Wildcard, record("*"))
+	o.On("order.*", record("order.*"))
+
+	events := []observable.Event{"user.created", "user.profile.updated", "user", "userx.created"}
+	for _, event := range events {
+		_ = o.Emit(event, string(event))
+	}
+	want := []string{
+		"user.*:user.created",
+		"user.created:user.created",
+		"*:user.created",
+		"user.*:user.profile.updated",
+		"*:user.profile.updated",
+		"*:user",
+		"*:userx.created",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("expected %v, got %v", want, calls)
+	}
+}
+
+func TestWildcardAsync(t *testing.T) {
+	o := observable.NewAsync(10)
+	o.Start()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	unsubscribe := o.On("user.*", func(params interface{}) error {
+		wg.Done()
+		return nil
+	})
+	for _, event := range []observable.Event{"user.created", "user.deleted"} {
+		if err := o.Emit(event, nil); err != nil {
+			t.Fatalf("emit failed: %v", err)
+		}
+	}
+	wg.Wait()
+	unsubscribe()
+}
@@ -0,0 +1,54 @@
+package observable
+
+import (
+	"errors"
+	"time"
+)
+
+// RetryPolicy retries the failed handler with backoff. The retries block the
+// caller of SyncObservable.Emit, or the worker of the AsyncObservable.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first.
+	MaxAttempts int
+
+	// Backoff returns the delay before the nth retry, starting from 1.
+	Backoff func(n int) time.Duration
+}
+
+// ExponentialBackoff doubles the delay for every retry, up to max.
+func ExponentialBackoff(base, max time.Duration) func(n int) time.Duration {
+	return func(n int) time.Duration {
+		d := base
+		for i := 1; i < n && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// DeadLetter is the event whose handler failed after exhausting the retries.
+type DeadLetter struct {
+	Event    Event
+	Params   interface{}
+	Err      error
+	Attempts int
+}
+
+// do calls fn until it succeeds or the attempts are exhausted, and returns
+// the last error with the number of attempts. Timed out handlers are not
+// retried, so that the retry does not run concurrently with the handler
+// that is still running.
+func (p RetryPolicy) do(fn func() error) (int, error) {
+	var err error
+	for n := 1; ; n++ {
+		if err = fn(); err == nil || n >= p.MaxAttempts || errors.Is(err, ErrHandlerTimeout) {
+			return n, err
+		}
+		if p.Backoff != nil {
+			time.Sleep(p.Backoff(n))
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package observable_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/observable"
+)
+
+func TestRetry(t *testing.T) {
+	o := observable.NewSync()
+
+	var n int
+	o.On("user.created", func(params interface{}) error {
+		n++
+		if n < 3 {
+			return errors.New("failed")
+		}
+		return nil
+	}, observable.WithRetry(observable.RetryPolicy{MaxAttempts: 3}))
+
+	if err := o.Emit("user.created", nil); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected %d attempts, got %d", 3, n)
+	}
+}
+
+func TestRetryDeadLetter(t *testing.T) {
+	var dead []observable.DeadLetter
+	o := observable.NewSync(observable.WithDeadLetter(func(msg observable.DeadLetter) {
+		dead = append(dead, msg)
+	}))
+
+	errFailed := errors.New("failed")
+	o.On("user.created", func(params interface{}) error {
+		return errFailed
+	}, observable.WithRetry(observable.RetryPolicy{
+		MaxAttempts: 2,
+		Backoff:     observable.ExponentialBackoff(time.Millisecond, time.Millisecond),
+	}))
+
+	if err := o.Emit("user.created", "john"); !errors.Is(err, errFailed) {
+		t.Fatalf("expected %v, got %v", errFailed, err)
+	}
+	want := []observable.DeadLetter{{Event: "user.created", Params: "john", Err: errFailed, Attempts: 2}}
+	if !reflect.DeepEqual(dead, want) {
+		t.Fatalf("expected %v, got %v", want, dead)
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+
+	for _, join := range []bool{false, true} {
+		var opts []observable.Option
+		if join {
+			opts = append(opts, observable.WithJoinErrors())
+		}
+		o := observable.NewSync(opts...)
+
+		var calls int
+		for _, err := range []error{errFirst, nil, errSecond} {
+			err := err
+			o.On("user.created", func(params interface{}) error {
+				calls++
+				return err
+			})
+		}
+
+		err := o.Emit("user.created", nil)
+		if !errors.Is(err, errFirst) {
+			t.Fatalf("expected %v, got %v", errFirst, err)
+		}
+		if got := errors.Is(err, errSecond); got != join {
+			t.Fatalf("expected errors.Is(err, errSecond) to be %t, got %t", join, got)
+		}
+		if want := map[bool]int{false: 1, true: 3}[join]; calls != want {
+			t.Fatalf("expected %d calls, got %d", want, calls)
+		}
+	}
+}
+
+func TestAsyncErrorHandler(t *testing.T) {
+	type report struct {
+		event observable.Event
+		err   error
+	}
+	reports := make(chan report, 10)
+	dead := make(chan observable.DeadLetter, 10)
+	o := observable.NewAsync(10,
+		observable.WithErrorHandler(func(event observable.Event, err error) {
+			reports <- report{event, err}
+		}),
+		observable.WithDeadLetter(func(msg observable.DeadLetter) {
+			dead <- msg
+		}),
+	)
+
+	var attempts int
+	o.On("user.created", func(params interface{}) error {
+		attempts++
+		panic("boom")
+	}, observable.WithRetry(observable.RetryPolicy{MaxAttempts: 3}))
+	o.Start()
+	if err := o.Emit("user.created", nil); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+	if err := o.Emit("user.deleted", nil); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+	o.Stop()
+
+	if r := <-reports; r.event != "user.created" || !errors.Is(r.err, observable.ErrHandlerPanic) {
+		t.Fatalf("expected panic to be reported, got %v", r)
+	}
+	if r := <-reports; r.event != "user.deleted" {
+		t.Fatalf("expected unregistered event to be reported, got %v", r)
+	}
+	if msg := <-dead; msg.Attempts != 3 || attempts != 3 {
+		t.Fatalf("expected %d attempts, got %d", 3, msg.Attempts)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := observable.ExponentialBackoff(100*time.Millisecond, time.Second)
+
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, d := range want {
+		if got := backoff(i + 1); got != d {
+			t.Fatalf("retry %d: expected %v, got %v", i+1, d, got)
+		}
+	}
+}
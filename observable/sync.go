@@ -1,45 +1,54 @@
 package observable
 
 import (
+	"errors"
 	"fmt"
-	"sync"
 )
 
 type SyncObservable struct {
-	sync.RWMutex
-	events map[Event][]Action
+	subs *registry
+	opts *options
 }
 
-func NewSync() *SyncObservable {
-	events := make(map[Event][]Action)
-	return &SyncObservable{events: events}
+func NewSync(opts ...Option) *SyncObservable {
+	return &SyncObservable{
+		subs: newRegistry(),
+		opts: newOptions(opts),
+	}
 }
 
 func (o *SyncObservable) Start() {}
 func (o *SyncObservable) Stop()  {}
 
-func (o *SyncObservable) On(event Event, fn Action) {
-	o.Lock()
-	_, exist := o.events[event]
-	if !exist {
-		o.events[event] = make([]Action, 0)
-	}
-	o.events[event] = append(o.events[event], fn)
-	o.Unlock()
+func (o *SyncObservable) On(event Event, fn Action, opts ...SubscribeOption) func() {
+	return o.subs.add(event, fn, false, opts...)
+}
+
+func (o *SyncObservable) Once(event Event, fn Action, opts ...SubscribeOption) func() {
+	return o.subs.add(event, fn, true, opts...)
 }
 
 func (o *SyncObservable) Emit(event Event, params interface{}) error {
-	o.RLock()
-	fns, exist := o.events[event]
-	o.RUnlock()
-	if !exist {
+	subs := o.subs.match(event)
+	if len(subs) == 0 {
 		return fmt.Errorf(`event "%s" does not exist`, event)
 	}
-	for _, fn := range fns {
-		err := fn(params)
-		if err != nil {
+	var errs []error
+	for _, s := range subs {
+		if !o.subs.claim(s) {
+			continue
+		}
+		n, err := s.retry.do(func() error {
+			return s.fn(params)
+		})
+		if err == nil {
+			continue
+		}
+		o.opts.exhausted(event, params, n, err)
+		if !o.opts.joinErrors {
 			return err
 		}
+		errs = append(errs, err)
 	}
-	return nil
+	return errors.Join(errs...)
 }
@@ -0,0 +1,62 @@
+package observable
+
+import (
+	"context"
+	"fmt"
+)
+
+// Topic is a typed event on an Observer, so that the payload types are
+// checked at compile time instead of being type-asserted by every Action.
+type Topic[T any] struct {
+	observer Observer
+	event    Event
+}
+
+// NewTopic returns a new Topic for the event on the observer. The dispatch
+// mode is the one of the observer, e.g. NewSync or NewAsync. For async
+// observers, the handlers may run after Publish returns, so the context
+// should not be cancelled when the caller returns, e.g. a request context.
+func NewTopic[T any](observer Observer, event Event) *Topic[T] {
+	return &Topic[T]{
+		observer: observer,
+		event:    event,
+	}
+}
+
+// envelope carries the context and payload through the observer.
+type envelope[T any] struct {
+	ctx context.Context
+	msg T
+}
+
+func (e envelope[T]) payload() interface{} {
+	return e.msg
+}
+
+// Payload returns the payload of the params, unwrapping the payloads
+// published on a Topic, e.g. in the partition key of WithPartitionKey or the
+// Params of the DeadLetter. Other params are returned as is.
+func Payload(params interface{}) interface{} {
+	if e, ok := params.(interface{ payload() interface{} }); ok {
+		return e.payload()
+	}
+	return params
+}
+
+// Subscribe registers the handler for the payloads of the topic, and returns
+// the function to unsubscribe.
+func (t *Topic[T]) Subscribe(fn func(ctx context.Context, msg T) error, opts ...SubscribeOption) func() {
+	return t.observer.On(t.event, func(params interface{}) error {
+		// The event may also be emitted directly on the observer.
+		e, ok := params.(envelope[T])
+		if !ok {
+			return fmt.Errorf(`event "%s": unexpected payload %T`, t.event, params)
+		}
+		return fn(e.ctx, e.msg)
+	}, opts...)
+}
+
+// Publish dispatches the payload to the handlers of the topic.
+func (t *Topic[T]) Publish(ctx context.Context, msg T) error {
+	return t.observer.Emit(t.event, envelope[T]{ctx: ctx, msg: msg})
+}
@@ -0,0 +1,133 @@
+package observable_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/alextanhongpin/pkg/observable"
+)
+
+type UserCreated struct {
+	ID   int
+	Name string
+}
+
+type contextKey string
+
+func TestTopicSync(t *testing.T) {
+	topic := observable.NewTopic[UserCreated](observable.NewSync(), "user.created")
+
+	var got []UserCreated
+	topic.Subscribe(func(ctx context.Context, msg UserCreated) error {
+		if ctx.Value(contextKey("request_id")) != "abc" {
+			t.Fatal("expected context to be passed to the handler")
+		}
+		got = append(got, msg)
+		return nil
+	})
+	ctx := context.WithValue(context.Background(), contextKey("request_id"), "abc")
+	if err := topic.Publish(ctx, UserCreated{ID: 1, Name: "john"}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "john" {
+		t.Fatalf("expected the payload to be handled, got %v", got)
+	}
+
+	errFailed := errors.New("failed")
+	topic.Subscribe(func(ctx context.Context, msg UserCreated) error {
+		return errFailed
+	})
+	if err := topic.Publish(ctx, UserCreated{ID: 2}); !errors.Is(err, errFailed) {
+		t.Fatalf("expected %v, got %v", errFailed, err)
+	}
+}
+
+func TestTopicAsync(t *testing.T) {
+	o := observable.NewAsync(10)
+	o.Start()
+	topic := observable.NewTopic[UserCreated](o, "user.created")
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ids []int
+	)
+	topic.Subscribe(func(ctx context.Context, msg UserCreated) error {
+		defer wg.Done()
+		mu.Lock()
+		ids = append(ids, msg.ID)
+		mu.Unlock()
+		return nil
+	})
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		if err := topic.Publish(context.Background(), UserCreated{ID: i}); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ids) != 3 {
+		t.Fatalf("expected 3 payloads to be handled, got %v", ids)
+	}
+}
+
+func TestTopicUnexpectedPayload(t *testing.T) {
+	o := observable.NewSync()
+	topic := observable.NewTopic[UserCreated](o, "user.created")
+	topic.Subscribe(func(ctx context.Context, msg UserCreated) error {
+		return nil
+	})
+	if err := o.Emit("user.created", "not a user"); err == nil {
+		t.Fatal("expected untyped payload to be rejected")
+	}
+}
+
+func TestTopicPartitionKey(t *testing.T) {
+	o := observable.NewAsync(10,
+		observable.WithWorkers(4),
+		observable.WithPartitionKey(func(event observable.Event, params interface{}) string {
+			msg, ok := observable.Payload(params).(UserCreated)
+			if !ok {
+				t.Errorf("unexpected payload %T", params)
+				return event.String()
+			}
+			return msg.Name
+		}),
+	)
+	topic := observable.NewTopic[UserCreated](o, "user.created")
+
+	var mu sync.Mutex
+	got := make(map[string][]int)
+	topic.Subscribe(func(ctx context.Context, msg UserCreated) error {
+		mu.Lock()
+		got[msg.Name] = append(got[msg.Name], msg.ID)
+		mu.Unlock()
+		return nil
+	})
+	o.Start()
+
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		name := []string{"john", "jane", "alice"}[i%3]
+		if err := topic.Publish(ctx, UserCreated{ID: i, Name: name}); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+	o.Stop()
+
+	for name, ids := range got {
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Fatalf("expected the messages of %s in order, got %v", name, ids)
+			}
+		}
+	}
+	if n := len(got["john"]) + len(got["jane"]) + len(got["alice"]); n != 100 {
+		t.Fatalf("expected %d messages, got %d", 100, n)
+	}
+}
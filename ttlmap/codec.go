@@ -0,0 +1,45 @@
+package ttlmap
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"io"
+)
+
+// Codec serialises the snapshot entries. Values stored as interface{} must be
+// registered with gob.Register when using GobCodec, while JSONCodec decodes
+// them into the generic json types, e.g. float64 and map[string]interface{}.
+type Codec interface {
+	Encode(w io.Writer, entries []Entry) error
+	Decode(r io.Reader) ([]Entry, error)
+}
+
+// GobCodec encodes the snapshot with encoding/gob. This is the default codec.
+type GobCodec struct{}
+
+func (GobCodec) Encode(w io.Writer, entries []Entry) error {
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+func (GobCodec) Decode(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// JSONCodec encodes the snapshot with encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(w io.Writer, entries []Entry) error {
+	return json.NewEncoder(w).Encode(entries)
+}
+
+func (JSONCodec) Decode(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
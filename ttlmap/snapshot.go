@@ -0,0 +1,139 @@
+package ttlmap
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry is the serialised form of an item. The expiry time is stored as an
+// absolute time so that the remaining lifetime is preserved across restarts.
+type Entry struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+	// ExpiresAt is zero for keys without ttl.
+	ExpiresAt time.Time     `json:"expiresAt,omitempty"`
+	TTL       time.Duration `json:"ttl,omitempty"`
+	Sliding   bool          `json:"sliding,omitempty"`
+}
+
+// Save writes all unexpired entries to the writer using the configured codec.
+func (t *TTLMap) Save(w io.Writer) error {
+	return t.codec.Encode(w, t.entries())
+}
+
+// Load reads the entries from the reader and adds them to the map. Entries
+// that have already expired are skipped, and existing keys are overwritten.
+func (t *TTLMap) Load(r io.Reader) error {
+	entries, err := t.codec.Decode(r)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	t.Lock()
+	defer t.Unlock()
+	for _, e := range entries {
+		it := item{value: e.Value, updatedAt: now, ttl: -1}
+		if e.TTL > 0 {
+			if !now.Before(e.ExpiresAt) {
+				continue
+			}
+			it.ttl = e.TTL
+			it.updatedAt = e.ExpiresAt.Add(-e.TTL)
+			it.sliding = e.Sliding
+		}
+		t.values[e.Key] = it
+	}
+	return nil
+}
+
+// SaveFile atomically writes the snapshot to the given path.
+func (t *TTLMap) SaveFile(path string) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := t.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// LoadFile restores the snapshot from the given path. A missing file is not
+// treated as an error, since there is nothing to restore on the first run.
+func (t *TTLMap) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return t.Load(f)
+}
+
+func (t *TTLMap) entries() []Entry {
+	now := time.Now()
+
+	t.RLock()
+	defer t.RUnlock()
+
+	entries := make([]Entry, 0, len(t.values))
+	for key, it := range t.values {
+		if it.expired(now) {
+			continue
+		}
+		e := Entry{Key: key, Value: it.value}
+		if it.ttl > 0 {
+			e.TTL = it.ttl
+			e.ExpiresAt = it.updatedAt.Add(it.ttl)
+			e.Sliding = it.sliding
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func (t *TTLMap) snapshot(interval time.Duration) {
+	defer t.wg.Done()
+
+	// A non-positive interval only snapshots on cancel.
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-t.quit:
+			t.saveSnapshot()
+			return
+		case <-tick:
+			t.saveSnapshot()
+		}
+	}
+}
+
+func (t *TTLMap) saveSnapshot() {
+	if err := t.SaveFile(t.snapshotPath); err != nil {
+		t.onError(err)
+	}
+}
@@ -0,0 +1,73 @@
+package ttlmap_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/pkg/ttlmap"
+)
+
+func TestSaveLoad(t *testing.T) {
+	codecs := map[string]ttlmap.Codec{
+		"gob":  ttlmap.GobCodec{},
+		"json": ttlmap.JSONCodec{},
+	}
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			src, cancel := ttlmap.New(ttlmap.WithCodec(codec))
+			defer cancel()
+
+			src.Set("forever", "a")
+			src.SetEx("ttl", "b", time.Minute)
+			src.SetEx("expired", "c", time.Nanosecond)
+			time.Sleep(time.Millisecond)
+
+			var buf bytes.Buffer
+			if err := src.Save(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			dst, cancel := ttlmap.New(ttlmap.WithCodec(codec))
+			defer cancel()
+			if err := dst.Load(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			if v, ok := dst.Get("forever"); !ok || v != "a" {
+				t.Fatalf("expected forever key to be restored, got %v", v)
+			}
+			if ttl, ok := dst.TTL("forever"); !ok || ttl >= 0 {
+				t.Fatalf("expected forever key without ttl, got %v", ttl)
+			}
+			if ttl, ok := dst.TTL("ttl"); !ok || ttl <= 0 || ttl > time.Minute {
+				t.Fatalf("expected remaining ttl to be preserved, got %v", ttl)
+			}
+			if _, ok := dst.Get("expired"); ok {
+				t.Fatalf("expected expired key to be skipped")
+			}
+		})
+	}
+}
+
+func TestSnapshotOnCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.gob")
+
+	src, cancel := ttlmap.New(ttlmap.WithSnapshot(path, 0))
+	src.SetEx("key", 1, time.Minute)
+	cancel()
+
+	dst, cancel := ttlmap.New()
+	defer cancel()
+	if err := dst.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := dst.Get("key"); !ok || v != 1 {
+		t.Fatalf("expected key to be restored from snapshot, got %v", v)
+	}
+
+	if err := dst.LoadFile(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Fatalf("expected missing snapshot to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package ttlmap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrNotFound is returned when the key does not exist in either tier.
+var ErrNotFound = errors.New("ttlmap: key not found")
+
+// Tiered is a two-tier cache with a local TTLMap (L1) in front of redis (L2).
+// Reads go through both tiers, writes go to redis first, and invalidations
+// are broadcasted over redis pub/sub so that other instances drop their local
+// copies. Since pub/sub messages may be lost, the local ttl bounds how long a
+// local copy may be stale.
+type Tiered struct {
+	// gen is incremented on every invalidation, and prevents a read-through
+	// from repopulating the local cache with a value that was invalidated
+	// while it was being fetched.
+	gen uint64
+
+	client      *redis.Client
+	pubsub      *redis.PubSub
+	local       *TTLMap
+	cancelLocal func()
+
+	id       string
+	channel  string
+	localTTL time.Duration
+
+	sync.Once
+	wg sync.WaitGroup
+}
+
+// TieredOption configures the Tiered cache.
+type TieredOption func(*Tiered)
+
+// WithLocalTTL sets the maximum duration a value is kept in the local cache.
+// Defaults to 1 minute.
+func WithLocalTTL(ttl time.Duration) TieredOption {
+	return func(t *Tiered) {
+		t.localTTL = ttl
+	}
+}
+
+// WithChannel sets the redis pub/sub channel for invalidations. Defaults to
+// "ttlmap:invalidate".
+func WithChannel(channel string) TieredOption {
+	return func(t *Tiered) {
+		t.channel = channel
+	}
+}
+
+// NewTiered returns a new two-tier cache, and a function to stop listening
+// to invalidations. It returns an error if the subscription to the
+// invalidation channel fails.
+func NewTiered(ctx context.Context, client *redis.Client, opts ...TieredOption) (*Tiered, func(), error) {
+	local, cancelLocal := New()
+	t := &Tiered{
+		client:      client,
+		local:       local,
+		cancelLocal: cancelLocal,
+		id:          newNodeID(),
+		channel:     "ttlmap:invalidate",
+		localTTL:    time.Minute,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	// Wait for the subscription to be confirmed, so that no invalidations
+	// are missed once the constructor returns.
+	t.pubsub = client.Subscribe(ctx, t.channel)
+	if _, err := t.pubsub.Receive(ctx); err != nil {
+		t.pubsub.Close()
+		cancelLocal()
+		return nil, nil, err
+	}
+
+	t.wg.Add(1)
+	go t.subscribe()
+
+	return t, t.cancel, nil
+}
+
+// Get returns the value from the local cache, or from redis if it is not
+// cached locally. The value is a copy, so the caller may modify it.
+func (t *Tiered) Get(ctx context.Context, key string) ([]byte, error) {
+	if v, ok := t.local.Get(key); ok {
+		return clone(v.([]byte)), nil
+	}
+
+	gen := atomic.LoadUint64(&t.gen)
+
+	var (
+		get  *redis.StringCmd
+		pttl *redis.DurationCmd
+	)
+	_, err := t.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := get.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Do not keep the local copy longer than the remote copy.
+	ttl := t.localTTL
+	if d := pttl.Val(); d > 0 && d < ttl {
+		ttl = d
+	}
+
+	t.local.Lock()
+	if atomic.LoadUint64(&t.gen) == gen {
+		t.local.values[key] = item{value: b, updatedAt: time.Now(), ttl: ttl}
+	}
+	t.local.Unlock()
+
+	return clone(b), nil
+}
+
+// Set writes the value to redis with the given ttl, and invalidates the
+// local copies in all instances. A zero ttl means the key has no expiration.
+//
+// The value is not cached locally, since another instance may overwrite it
+// before it is cached, and its invalidation may arrive first. The next Get
+// reads through instead.
+func (t *Tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := t.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return err
+	}
+	t.invalidate(key)
+	return t.publish(ctx, key)
+}
+
+// Delete removes the key from redis, and invalidates the local copies in all
+// instances.
+func (t *Tiered) Delete(ctx context.Context, key string) error {
+	if err := t.client.Del(ctx, key).Err(); err != nil {
+		return err
+	}
+	t.invalidate(key)
+	return t.publish(ctx, key)
+}
+
+func (t *Tiered) publish(ctx context.Context, key string) error {
+	return t.client.Publish(ctx, t.channel, t.id+" "+key).Err()
+}
+
+func (t *Tiered) invalidate(key string) {
+	t.local.Lock()
+	atomic.AddUint64(&t.gen, 1)
+	delete(t.local.values, key)
+	t.local.Unlock()
+}
+
+func (t *Tiered) flush() {
+	t.local.Lock()
+	atomic.AddUint64(&t.gen, 1)
+	t.local.values = make(map[string]item)
+	t.local.Unlock()
+}
+
+func (t *Tiered) subscribe() {
+	defer t.wg.Done()
+
+	for msg := range t.pubsub.ChannelWithSubscriptions(context.Background(), 100) {
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			// Invalidations may have been missed while the connection
+			// was down, so drop everything after resubscribing.
+			if m.Kind == "subscribe" {
+				t.flush()
+			}
+		case *redis.Message:
+			parts := strings.SplitN(m.Payload, " ", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			// Skip our own invalidations, which are applied locally.
+			if id, key := parts[0], parts[1]; id != t.id {
+				t.invalidate(key)
+			}
+		}
+	}
+}
+
+func (t *Tiered) cancel() {
+	t.Once.Do(func() {
+		t.pubsub.Close()
+		t.wg.Wait()
+		t.cancelLocal()
+	})
+}
+
+func clone(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
+
+func newNodeID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,173 @@
+package ttlmap_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/alextanhongpin/pkg/ttlmap"
+)
+
+func TestTiered(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var (
+		ctx = context.Background()
+		a   = newTiered(t, s)
+		b   = newTiered(t, s)
+	)
+
+	if _, err := a.Get(ctx, "key"); !errors.Is(err, ttlmap.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := a.Set(ctx, "key", []byte("v1"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// Let b receive the invalidation before populating its local cache.
+	time.Sleep(50 * time.Millisecond)
+	assertGet(t, b, "key", "v1")
+
+	// Changing the value in redis directly is not visible to the local
+	// cache of b.
+	if err := s.Set("key", "stale"); err != nil {
+		t.Fatal(err)
+	}
+	assertGet(t, b, "key", "v1")
+
+	// Writes through a invalidate the local copy in b.
+	if err := a.Set(ctx, "key", []byte("v2"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		v, err := b.Get(ctx, "key")
+		return err == nil && bytes.Equal(v, []byte("v2"))
+	})
+
+	if err := a.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		_, err := b.Get(ctx, "key")
+		return errors.Is(err, ttlmap.ErrNotFound)
+	})
+}
+
+func TestTieredConcurrentWriters(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var (
+		ctx   = context.Background()
+		nodes = []*ttlmap.Tiered{newTiered(t, s), newTiered(t, s)}
+	)
+
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node *ttlmap.Tiered) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				value := []byte(fmt.Sprintf("node %d: %d", i, j))
+				if err := node.Set(ctx, "key", value, time.Hour); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := node.Get(ctx, "key"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i, node)
+	}
+	wg.Wait()
+
+	// Both nodes converge on the last write, instead of keeping their own
+	// last write for the local ttl.
+	want, err := s.Get("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range nodes {
+		eventually(t, func() bool {
+			v, err := node.Get(ctx, "key")
+			return err == nil && string(v) == want
+		})
+	}
+}
+
+func TestTieredGetCopy(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	cache := newTiered(t, s)
+	if err := cache.Set(ctx, "key", []byte("value"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modify both the read-through and the locally cached value.
+	for i := 0; i < 2; i++ {
+		v, err := cache.Get(ctx, "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v[0] = 'X'
+	}
+	assertGet(t, cache, "key", "value")
+}
+
+func newTiered(t *testing.T, s *miniredis.Miniredis) *ttlmap.Tiered {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cache, cancel, err := ttlmap.NewTiered(context.Background(), client, ttlmap.WithLocalTTL(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cancel)
+	return cache
+}
+
+func assertGet(t *testing.T, cache *ttlmap.Tiered, key, want string) {
+	t.Helper()
+
+	got, err := cache.Get(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func eventually(t *testing.T, fn func() bool) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if fn() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
@@ -1,6 +1,7 @@
 package ttlmap
 
 import (
+	"log"
 	"sync"
 	"time"
 )
@@ -15,6 +16,13 @@ type item struct {
 	value     interface{}
 	updatedAt time.Time
 	ttl       time.Duration
+
+	// sliding extends the lifetime of the item by ttl every time it is read.
+	sliding bool
+}
+
+func (i item) expired(now time.Time) bool {
+	return i.ttl > 0 && now.Sub(i.updatedAt) > i.ttl
 }
 
 type TTLMap struct {
@@ -25,14 +33,60 @@ type TTLMap struct {
 	quit chan interface{}
 
 	wg sync.WaitGroup
+
+	codec            Codec
+	snapshotPath     string
+	snapshotInterval time.Duration
+	onError          func(error)
+}
+
+// Option configures the TTLMap.
+type Option func(*TTLMap)
+
+// WithCodec sets the codec used by Save and Load. Defaults to GobCodec.
+func WithCodec(codec Codec) Option {
+	return func(t *TTLMap) {
+		t.codec = codec
+	}
+}
+
+// WithSnapshot periodically saves the map to the given path, and once more
+// when the map is cancelled. A non-positive interval only saves on cancel.
+// Use LoadFile to restore the snapshot on startup.
+func WithSnapshot(path string, interval time.Duration) Option {
+	return func(t *TTLMap) {
+		t.snapshotPath = path
+		t.snapshotInterval = interval
+	}
+}
+
+// WithErrorHandler sets the handler for errors from background snapshots.
+// Defaults to logging the error.
+func WithErrorHandler(fn func(error)) Option {
+	return func(t *TTLMap) {
+		t.onError = fn
+	}
 }
 
-func New() (*TTLMap, func()) {
+func New(opts ...Option) (*TTLMap, func()) {
 	ttl := &TTLMap{
 		values: make(map[string]item),
 		quit:   make(chan interface{}),
+		codec:  GobCodec{},
+		onError: func(err error) {
+			log.Println("[ttlmap] snapshot error:", err)
+		},
 	}
+	for _, opt := range opts {
+		opt(ttl)
+	}
+	ttl.wg.Add(1)
 	go ttl.clear(5 * time.Second)
+
+	if ttl.snapshotPath != "" {
+		ttl.wg.Add(1)
+		go ttl.snapshot(ttl.snapshotInterval)
+	}
 	return ttl, ttl.cancel
 }
 
@@ -51,12 +105,24 @@ func (t *TTLMap) Get(key string) (interface{}, bool) {
 	if !ok {
 		return nil, ok
 	}
-	t.Lock()
-	if res.ttl > 0 && time.Since(res.updatedAt) > res.ttl {
-		delete(t.values, key)
+	now := time.Now()
+	if res.expired(now) {
+		t.Lock()
+		// Only delete the key if it has not been replaced in the meantime.
+		if cur, ok := t.values[key]; ok && cur.expired(now) {
+			delete(t.values, key)
+		}
+		t.Unlock()
 		return nil, false
 	}
-	t.Unlock()
+	if res.sliding {
+		t.Lock()
+		if cur, ok := t.values[key]; ok && cur.sliding {
+			cur.updatedAt = now
+			t.values[key] = cur
+		}
+		t.Unlock()
+	}
 	return res.value, true
 }
 
@@ -66,11 +132,128 @@ func (t *TTLMap) SetEx(key string, value interface{}, duration time.Duration) {
 	t.Unlock()
 }
 
+// SetNX sets the key with the given ttl only if the key does not exist or has
+// expired. It returns false if the key already exists.
+func (t *TTLMap) SetNX(key string, value interface{}, duration time.Duration) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	now := time.Now()
+	if it, ok := t.values[key]; ok && !it.expired(now) {
+		return false
+	}
+	t.values[key] = item{value: value, updatedAt: now, ttl: duration}
+	return true
+}
+
+// Delete removes the key from the map.
+func (t *TTLMap) Delete(key string) {
+	t.Lock()
+	delete(t.values, key)
+	t.Unlock()
+}
+
+// Flush removes all keys from the map.
+func (t *TTLMap) Flush() {
+	t.Lock()
+	t.values = make(map[string]item)
+	t.Unlock()
+}
+
+// SetSliding sets the key with a sliding expiration. Every successful Get
+// resets the lifetime of the key to the given duration, so the key only
+// expires after being idle for the duration, e.g. user sessions.
+func (t *TTLMap) SetSliding(key string, value interface{}, duration time.Duration) {
+	t.Lock()
+	t.values[key] = item{value: value, updatedAt: time.Now(), ttl: duration, sliding: true}
+	t.Unlock()
+}
+
+// Touch resets the lifetime of the key without modifying the value or the
+// ttl. It returns false if the key does not exist or has already expired.
+func (t *TTLMap) Touch(key string) bool {
+	return t.update(key, func(it *item) {})
+}
+
+// Expire sets a new ttl for the key, starting from now. Similar to redis, a
+// non-positive ttl deletes the key immediately. It returns false if the key
+// does not exist or has already expired.
+func (t *TTLMap) Expire(key string, ttl time.Duration) bool {
+	if ttl <= 0 {
+		return t.delete(key)
+	}
+	return t.update(key, func(it *item) {
+		it.ttl = ttl
+	})
+}
+
+// Persist removes the ttl of the key, so that it never expires. It returns
+// false if the key does not exist, has already expired, or does not have a
+// ttl.
+func (t *TTLMap) Persist(key string) bool {
+	var persisted bool
+	t.update(key, func(it *item) {
+		persisted = it.ttl > 0
+		it.ttl = -1
+		it.sliding = false
+	})
+	return persisted
+}
+
+// TTL returns the remaining time to live of the key. A negative duration is
+// returned for keys without ttl. It returns false if the key does not exist
+// or has already expired.
+func (t *TTLMap) TTL(key string) (time.Duration, bool) {
+	t.RLock()
+	it, ok := t.values[key]
+	t.RUnlock()
+
+	now := time.Now()
+	if !ok || it.expired(now) {
+		return 0, false
+	}
+	if it.ttl <= 0 {
+		return -1, true
+	}
+	return it.ttl - now.Sub(it.updatedAt), true
+}
+
+// update applies fn to an unexpired key and resets its lifetime.
+func (t *TTLMap) update(key string, fn func(*item)) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	now := time.Now()
+	it, ok := t.values[key]
+	if !ok {
+		return false
+	}
+	if it.expired(now) {
+		delete(t.values, key)
+		return false
+	}
+	fn(&it)
+	it.updatedAt = now
+	t.values[key] = it
+	return true
+}
+
+func (t *TTLMap) delete(key string) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	it, ok := t.values[key]
+	if !ok {
+		return false
+	}
+	delete(t.values, key)
+	return !it.expired(time.Now())
+}
+
 func (t *TTLMap) clear(duration time.Duration) {
 	ticker := time.NewTicker(duration)
 	defer ticker.Stop()
 
-	t.wg.Add(1)
 	defer t.wg.Done()
 	for {
 		select {
@@ -79,8 +262,9 @@ func (t *TTLMap) clear(duration time.Duration) {
 		case <-ticker.C:
 			t.Lock()
 			var i int
+			now := time.Now()
 			for key, item := range t.values {
-				if item.ttl > 0 && time.Since(item.updatedAt) > item.ttl {
+				if item.expired(now) {
 					i++
 					delete(t.values, key)
 				}
@@ -2,6 +2,7 @@ package ttlmap_test
 
 import (
 	"log"
+	"testing"
 	"time"
 
 	"github.com/alextanhongpin/pkg/ttlmap"
@@ -33,3 +34,89 @@ func Example() {
 	}
 	log.Println("got val", val)
 }
+
+func TestSliding(t *testing.T) {
+	m, cancel := ttlmap.New()
+	defer cancel()
+
+	m.SetSliding("session", "alice", 50*time.Millisecond)
+	for i := 0; i < 4; i++ {
+		time.Sleep(30 * time.Millisecond)
+		if _, ok := m.Get("session"); !ok {
+			t.Fatalf("expected sliding key to be kept alive by Get")
+		}
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := m.Get("session"); ok {
+		t.Fatalf("expected idle sliding key to expire")
+	}
+}
+
+func TestExpirePersistTouch(t *testing.T) {
+	m, cancel := ttlmap.New()
+	defer cancel()
+
+	if m.Touch("missing") || m.Expire("missing", time.Second) || m.Persist("missing") {
+		t.Fatalf("expected operations on missing key to return false")
+	}
+
+	m.Set("key", 1)
+	if _, ok := m.TTL("key"); !ok {
+		t.Fatalf("expected key to exist")
+	}
+	if m.Persist("key") {
+		t.Fatalf("expected persist on key without ttl to return false")
+	}
+
+	if !m.Expire("key", 50*time.Millisecond) {
+		t.Fatalf("expected expire to return true")
+	}
+	if ttl, _ := m.TTL("key"); ttl <= 0 || ttl > 50*time.Millisecond {
+		t.Fatalf("expected ttl to be within 50ms, got %v", ttl)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !m.Touch("key") {
+		t.Fatalf("expected touch to return true")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := m.Get("key"); !ok {
+		t.Fatalf("expected touched key to be present")
+	}
+
+	if !m.Persist("key") {
+		t.Fatalf("expected persist to return true")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if ttl, ok := m.TTL("key"); !ok || ttl >= 0 {
+		t.Fatalf("expected persisted key without ttl, got %v %t", ttl, ok)
+	}
+
+	if !m.Expire("key", 0) {
+		t.Fatalf("expected non-positive expire to delete the key")
+	}
+	if _, ok := m.Get("key"); ok {
+		t.Fatalf("expected key to be deleted")
+	}
+}
+
+func TestSetNX(t *testing.T) {
+	m, cancel := ttlmap.New()
+	defer cancel()
+
+	if !m.SetNX("key", 1, 50*time.Millisecond) {
+		t.Fatalf("expected new key to be set")
+	}
+	if m.SetNX("key", 2, time.Minute) {
+		t.Fatalf("expected existing key not to be set")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !m.SetNX("key", 3, time.Minute) {
+		t.Fatalf("expected expired key to be set")
+	}
+	if v, _ := m.Get("key"); v != 3 {
+		t.Fatalf("expected 3, got %v", v)
+	}
+}